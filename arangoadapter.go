@@ -3,15 +3,21 @@
 package arangoadapter
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
-	"strings"
+	"fmt"
+	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/arangodb/go-driver/v2/arangodb"
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/persist"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -19,6 +25,13 @@ const (
 	defaultCollectionName = "casbin_rule"
 )
 
+// ErrRuleNotFound is returned by the remove methods when WithErrOnNoMatch is enabled
+// and no document matched the rule or filter.
+var ErrRuleNotFound = errors.New("rule not found")
+
+// fieldNames are the document attributes holding the rule values, in order.
+var fieldNames = [...]string{"v0", "v1", "v2", "v3", "v4", "v5"}
+
 // CasbinRule represents a single policy rule in ArangoDB.
 // Casbin supports up to 6 values per rule, so we've got V0 through V5.
 type CasbinRule struct {
@@ -30,6 +43,19 @@ type CasbinRule struct {
 	V3    string `json:"v3"`
 	V4    string `json:"v4"`
 	V5    string `json:"v5"`
+
+	ExpiresAt string `json:"expiresAt,omitempty"` // When the rule stops applying (ISO 8601 UTC), empty if never
+
+	// Metadata, filled in by the adapter and ignored by Casbin
+	CreatedAt string            `json:"createdAt,omitempty"` // ISO 8601 UTC
+	UpdatedAt string            `json:"updatedAt,omitempty"` // ISO 8601 UTC
+	CreatedBy string            `json:"createdBy,omitempty"` // Actor from the context, see WithActor
+	Labels    map[string]string `json:"labels,omitempty"`    // Free-form annotations, see WithLabels
+}
+
+// values returns V0 through V5 in order.
+func (r CasbinRule) values() []string {
+	return []string{r.V0, r.V1, r.V2, r.V3, r.V4, r.V5}
 }
 
 // Filter lets you query policies based on specific field values.
@@ -42,6 +68,9 @@ type Filter struct {
 	V3    []string
 	V4    []string
 	V5    []string
+
+	// Conditions compare values stored natively through a ValueCodec, e.g. a numeric level
+	Conditions []Condition
 }
 
 // BatchFilter wraps multiple filters for batch operations.
@@ -52,15 +81,42 @@ type BatchFilter struct {
 // Adapter is the main struct that connects Casbin to ArangoDB.
 // It handles all the CRUD operations for policy rules.
 type Adapter struct {
-	client         arangodb.Client
-	db             arangodb.Database
-	collection     arangodb.Collection
-	databaseName   string
-	collectionName string
-	isFiltered     bool
-	transaction    arangodb.Transaction // Active transaction, if any
-	transactionMu  *sync.Mutex
-	muInitialize   sync.Once
+	client                arangodb.Client
+	db                    arangodb.Database
+	collection            arangodb.Collection            // Main rule collection
+	collections           map[string]arangodb.Collection // Every rule collection by name, the main one included
+	databaseName          string
+	collectionName        string
+	routing               *routing // Which collection holds each ptype's rules
+	isFiltered            bool
+	errOnNoMatch          bool                // Return ErrRuleNotFound when a remove matches nothing
+	softDelete            bool                // Flag removed rules with deletedAt instead of deleting them
+	readOnly              bool                // Open existing collections as they are, see WithReadOnly
+	lenientLoad           bool                // Skip rules that can't be loaded instead of failing
+	mapping               *mapping            // Which document attributes hold the ptype and values
+	retryPolicy           *RetryPolicy        // Retry policy for failed requests, nil disables retries
+	tracer                trace.Tracer        // Tracer for operation spans, no-op unless configured
+	metrics               MetricsRecorder     // Metrics hook, no-op unless configured
+	sizeInterval          time.Duration       // Minimum time between collection size refreshes, negative for never
+	sizeRefreshed         *atomic.Int64       // When the collection size was last refreshed, in Unix nanoseconds
+	logger                *slog.Logger        // Structured logger, silent unless configured
+	logQueries            bool                // Log AQL queries at debug level
+	redactFields          []string            // Rule fields redacted from query logs
+	sortIndexes           []string            // Rule fields ListRules sorts by with an index
+	expiry                bool                // Add the TTL index to every rule collection up front
+	expiryIndexed         *sync.Map           // Names of the rule collections known to have the TTL index
+	auditCollection       arangodb.Collection // Audit log collection, nil unless auditing is enabled
+	auditCollectionName   string
+	historyCollection     arangodb.Collection // Revision history collection, nil unless versioning is enabled
+	historyCollectionName string
+	validationLevel       arangodb.CollectionSchemaLevel // Schema validation level for rule collections, empty if disabled
+	onSkipped             func(skipped []SkippedRule)    // Called with the rules a lenient load skipped (optional)
+	metaCollection        arangodb.Collection            // Metadata collection holding the schema version
+	metaCollectionName    string
+	stopExpiryWatcher     context.CancelFunc   // Stops the expiry watcher, nil unless one is running
+	transaction           arangodb.Transaction // Active transaction, if any
+	transactionMu         *sync.Mutex
+	muInitialize          sync.Once
 }
 
 // NewAdapter creates a new ArangoDB adapter using functional options.
@@ -78,25 +134,17 @@ func NewAdapter(opts ...Option) (*Adapter, error) {
 	cfg := NewConfig(opts...)
 	client, err := cfg.createConnection()
 	if err != nil {
+		newLogger(cfg).Error("failed to set up ArangoDB connection", slog.Any("error", err))
 		return nil, err
 	}
 
-	a := &Adapter{
-		client:         client,
-		databaseName:   cfg.DatabaseName,
-		collectionName: cfg.CollectionName,
-		transactionMu:  &sync.Mutex{},
-	}
-
-	if err := a.ensureDatabaseExists(); err != nil {
-		return nil, err
-	}
-
-	if err := a.ensureCollectionExists(); err != nil {
-		return nil, err
-	}
+	newLogger(cfg).Info("connecting to ArangoDB",
+		slog.Any("endpoints", cfg.Endpoints),
+		slog.String("username", cfg.Username),
+		slog.Bool("tls", cfg.TLSEnabled),
+	)
 
-	return a, nil
+	return newAdapter(client, cfg)
 }
 
 // NewFilteredAdapter creates a filtered adapter that won't auto-load all policies.
@@ -114,22 +162,91 @@ func NewFilteredAdapter(opts ...Option) (*Adapter, error) {
 // NewAdapterFromClient creates a new ArangoDB adapter from an existing client.
 // This is useful when you already have an ArangoDB client configured.
 // It'll automatically create the database and collection if they don't exist.
-func NewAdapterFromClient(client arangodb.Client, databaseName string, collectionName string) (*Adapter, error) {
+// Connection options like WithEndpoints are ignored, everything else still applies.
+func NewAdapterFromClient(client arangodb.Client, databaseName string, collectionName string, opts ...Option) (*Adapter, error) {
+	cfg := NewConfig(opts...)
+	cfg.DatabaseName = databaseName
+	cfg.CollectionName = collectionName
+
+	return newAdapter(client, cfg)
+}
+
+// newAdapter builds an adapter on top of client and makes sure the database and collection exist.
+func newAdapter(client arangodb.Client, cfg *Config) (*Adapter, error) {
 	a := &Adapter{
-		client:         client,
-		databaseName:   databaseName,
-		collectionName: collectionName,
-		transactionMu:  &sync.Mutex{},
+		client:          client,
+		databaseName:    cfg.DatabaseName,
+		collectionName:  cfg.CollectionName,
+		errOnNoMatch:    cfg.ErrOnNoMatch,
+		softDelete:      cfg.SoftDelete,
+		readOnly:        cfg.ReadOnly,
+		lenientLoad:     cfg.LenientLoad,
+		onSkipped:       cfg.LenientLoadHook,
+		retryPolicy:     cfg.Retry,
+		tracer:          newTracer(cfg.TracerProvider),
+		metrics:         nopMetrics{},
+		sizeInterval:    cmp.Or(cfg.CollectionSizeInterval, defaultCollectionSizeInterval),
+		sizeRefreshed:   &atomic.Int64{},
+		logger:          newLogger(cfg),
+		logQueries:      cfg.LogQueries,
+		redactFields:    cfg.RedactFields,
+		sortIndexes:     cfg.SortIndexes,
+		expiry:          cfg.Expiry,
+		expiryIndexed:   &sync.Map{},
+		validationLevel: cfg.SchemaValidation,
+		transactionMu:   &sync.Mutex{},
+	}
+	if cfg.Metrics != nil {
+		a.metrics = cfg.Metrics
+	}
+
+	m, err := newMapping(cfg)
+	if err != nil {
+		a.logger.Error("invalid field mapping", slog.Any("error", err))
+		return nil, err
+	}
+	a.mapping = m
+
+	r, err := newRouting(cfg)
+	if err != nil {
+		a.logger.Error("invalid collection routes", slog.Any("error", err))
+		return nil, err
 	}
+	a.routing = r
 
 	if err := a.ensureDatabaseExists(); err != nil {
+		a.logger.Error("failed to open database", slog.Any("error", err))
 		return nil, err
 	}
 
 	if err := a.ensureCollectionExists(); err != nil {
+		a.logger.Error("failed to open collection", slog.Any("error", err))
 		return nil, err
 	}
 
+	if err := a.ensureSchema(context.Background(), cfg); err != nil {
+		a.logger.Error("failed to check schema version", slog.Any("error", err))
+		return nil, err
+	}
+
+	if cfg.AuditEnabled {
+		if err := a.ensureAuditCollection(cfg.AuditCollectionName); err != nil {
+			a.logger.Error("failed to open audit collection", slog.Any("error", err))
+			return nil, err
+		}
+	}
+
+	if cfg.VersioningEnabled {
+		if err := a.ensureHistoryCollection(cfg.HistoryCollectionName); err != nil {
+			a.logger.Error("failed to open history collection", slog.Any("error", err))
+			return nil, err
+		}
+	}
+
+	if cfg.ExpiryHook != nil {
+		a.startExpiryWatcher(cfg.ExpiryInterval, cfg.ExpiryHook)
+	}
+
 	return a, nil
 }
 
@@ -139,32 +256,44 @@ func (a *Adapter) ensureDatabaseExists() error {
 
 	// Try to get the database first
 	db, err := a.client.Database(ctx, a.databaseName)
+	if err != nil && a.readOnly {
+		return err
+	}
 	if err != nil {
 		// Database doesn't exist, create it
 		db, err = a.client.CreateDatabase(ctx, a.databaseName, nil)
 		if err != nil {
 			return err
 		}
+		a.logger.Info("created database")
 	}
 	a.db = db
 	return nil
 }
 
-// ensureCollectionExists gets or creates the collection.
+// ensureCollectionExists gets or creates the collection, along with any collections
+// rules are routed to.
 func (a *Adapter) ensureCollectionExists() error {
-	ctx := context.Background()
+	return a.ensureRuleCollections(context.Background())
+}
 
+// ensureCollection gets or creates the named collection, creating it with props if given.
+// Read-only adapters only get it.
+func (a *Adapter) ensureCollection(ctx context.Context, name string, props *arangodb.CreateCollectionProperties) (arangodb.Collection, error) {
 	// Try to get the collection first
-	col, err := a.db.Collection(ctx, a.collectionName)
+	col, err := a.db.Collection(ctx, name)
+	if err != nil && a.readOnly {
+		return nil, err
+	}
 	if err != nil {
 		// Collection doesn't exist, create it
-		col, err = a.db.CreateCollection(ctx, a.collectionName, nil)
+		col, err = a.db.CreateCollection(ctx, name, props)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		a.logger.Info("created collection", slog.String("name", name))
 	}
-	a.collection = col
-	return nil
+	return col, nil
 }
 
 // loadPolicyLine converts a database rule into a Casbin policy line.
@@ -173,19 +302,42 @@ func loadPolicyLine(line CasbinRule, model model.Model) error {
 		return nil
 	}
 
-	// Build the policy array
-	var p []string
-	p = append(p, line.Ptype, line.V0, line.V1, line.V2, line.V3, line.V4, line.V5)
+	// Load into model
+	return persist.LoadPolicyArray(line.policyArray(), model)
+}
+
+// policyArray returns the rule the way Casbin represents it, the ptype followed by the values.
+func (r CasbinRule) policyArray() []string {
+	p := append([]string{r.Ptype}, r.values()...)
 
 	// Trim trailing empty fields since Casbin doesn't need them
 	index := len(p) - 1
-	for p[index] == "" {
+	for index > 0 && p[index] == "" {
 		index--
 	}
-	p = p[:index+1]
+	return p[:index+1]
+}
 
-	// Load into model
-	return persist.LoadPolicyArray(p, model)
+// query runs an AQL query, inside the active transaction if there is one.
+// Failed requests are retried according to the adapter's retry policy.
+func (a *Adapter) query(ctx context.Context, query string, bindVars map[string]interface{}) (cursor arangodb.Cursor, err error) {
+	ctx, span := a.startSpan(ctx, "query", attrDBQueryText.String(query))
+	defer func() { endSpan(span, err) }()
+
+	var db arangodb.DatabaseQuery = a.db
+	if a.transaction != nil {
+		db = a.transaction
+	}
+	a.logQuery(ctx, query, bindVars)
+
+	err = a.withRetry(ctx, func() error {
+		var err error
+		cursor, err = db.Query(ctx, query, &arangodb.QueryOptions{
+			BindVars: bindVars,
+		})
+		return err
+	})
+	return cursor, err
 }
 
 // LoadPolicy loads all policies from the database into the Casbin model.
@@ -195,36 +347,11 @@ func (a *Adapter) LoadPolicy(model model.Model) error {
 }
 
 // LoadPolicyCtx is like LoadPolicy but with context support for cancellation and timeouts.
+// With WithLenientLoad, rules that can't be loaded are skipped instead of failing the load.
 func (a *Adapter) LoadPolicyCtx(ctx context.Context, model model.Model) error {
-	query := "FOR doc IN @@collection RETURN doc"
-	bindVars := map[string]interface{}{
-		"@collection": a.collectionName,
-	}
-
-	cursor, err := a.db.Query(ctx, query, &arangodb.QueryOptions{
-		BindVars: bindVars,
-	})
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = cursor.Close()
-	}()
-
-	for cursor.HasMore() {
-		var rule CasbinRule
-		_, err := cursor.ReadDocument(ctx, &rule)
-		if err != nil {
-			return err
-		}
-
-		err = loadPolicyLine(rule, model)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	skipped, err := a.loadPolicy(ctx, model, a.lenientLoad)
+	a.reportSkipped(skipped)
+	return err
 }
 
 // LoadFilteredPolicy loads only policies that match the filter.
@@ -234,7 +361,7 @@ func (a *Adapter) LoadFilteredPolicy(model model.Model, filter interface{}) erro
 }
 
 // LoadFilteredPolicyCtx loads filtered policies with context support.
-func (a *Adapter) LoadFilteredPolicyCtx(ctx context.Context, model model.Model, filter interface{}) error {
+func (a *Adapter) LoadFilteredPolicyCtx(ctx context.Context, model model.Model, filter interface{}) (err error) {
 	// Handle different filter types
 	var filters []Filter
 	switch f := filter.(type) {
@@ -256,71 +383,34 @@ func (a *Adapter) LoadFilteredPolicyCtx(ctx context.Context, model model.Model,
 		return a.LoadPolicyCtx(ctx, model)
 	}
 
-	// Apply each filter and load matching policies
-	for _, f := range filters {
-		query := "FOR doc IN @@collection"
-		bindVars := map[string]interface{}{
-			"@collection": a.collectionName,
-		}
-
-		// Build filter conditions
-		conditions := []string{}
-		if len(f.Ptype) > 0 {
-			conditions = append(conditions, "doc.ptype IN @ptype")
-			bindVars["ptype"] = f.Ptype
-		}
-		if len(f.V0) > 0 {
-			conditions = append(conditions, "doc.v0 IN @v0")
-			bindVars["v0"] = f.V0
-		}
-		if len(f.V1) > 0 {
-			conditions = append(conditions, "doc.v1 IN @v1")
-			bindVars["v1"] = f.V1
-		}
-		if len(f.V2) > 0 {
-			conditions = append(conditions, "doc.v2 IN @v2")
-			bindVars["v2"] = f.V2
-		}
-		if len(f.V3) > 0 {
-			conditions = append(conditions, "doc.v3 IN @v3")
-			bindVars["v3"] = f.V3
-		}
-		if len(f.V4) > 0 {
-			conditions = append(conditions, "doc.v4 IN @v4")
-			bindVars["v4"] = f.V4
-		}
-		if len(f.V5) > 0 {
-			conditions = append(conditions, "doc.v5 IN @v5")
-			bindVars["v5"] = f.V5
-		}
-
-		// Add FILTER clause if we have conditions
-		if len(conditions) > 0 {
-			query += " FILTER " + strings.Join(conditions, " AND ")
+	ctx, op := a.startOperation(ctx, "LoadFilteredPolicy")
+	loaded := 0
+	defer func() {
+		op.setRuleCount(loaded)
+		if err == nil {
+			a.metrics.ObserveRulesLoaded(loaded)
 		}
-		query += " RETURN doc"
+		op.end(err)
+	}()
 
-		cursor, err := a.db.Query(ctx, query, &arangodb.QueryOptions{
-			BindVars: bindVars,
-		})
+	// Apply each filter and load matching policies
+	var skipped []SkippedRule
+	defer func() {
+		a.reportSkipped(skipped)
+	}()
+	for _, f := range filters {
+		conditions, bindVars, err := a.mapping.filterConditions(f)
 		if err != nil {
 			return err
 		}
 
-		for cursor.HasMore() {
-			var rule CasbinRule
-			_, err := cursor.ReadDocument(ctx, &rule)
-			if err != nil {
-				_ = cursor.Close()
-				return err
-			}
-
-			if err := loadPolicyLine(rule, model); err != nil {
-				_ = cursor.Close()
-				return err
-			}
+		// Only look in the collections that can hold the filtered ptypes
+		count, skippedByFilter, err := a.loadRules(ctx, model, a.routing.namesFor(f.Ptype), conditions, bindVars, a.lenientLoad)
+		loaded += count
+		skipped = append(skipped, skippedByFilter...)
+		if err != nil {
+			return err
 		}
-		_ = cursor.Close()
 	}
 
 	a.isFiltered = true
@@ -340,56 +430,87 @@ func (a *Adapter) SavePolicy(model model.Model) error {
 
 // SavePolicyCtx is like SavePolicy but with context support.
 // Uses batching to handle large policy sets efficiently.
-func (a *Adapter) SavePolicyCtx(ctx context.Context, model model.Model) error {
-	const batchSize = 1000
+func (a *Adapter) SavePolicyCtx(ctx context.Context, model model.Model) (err error) {
+	ctx, op := a.startWrite(ctx, "SavePolicy")
+	saved := 0
+	defer func() {
+		op.setRuleCount(saved)
+		op.end(err)
+	}()
 
-	// Clear everything out first
-	err := a.collection.Truncate(ctx)
-	if err != nil {
-		return err
+	lines := a.modelRules(model)
+	return a.recorded(ctx, func(tx *Adapter) error {
+		// The model doesn't know about expiry times or metadata, so carry them over from the stored rules
+		if err := tx.keepStored(ctx, lines); err != nil {
+			return err
+		}
+		stampCreated(ctx, lines)
+		if err := tx.replaceRules(ctx, lines); err != nil {
+			return err
+		}
+		saved = len(lines)
+		return tx.record(ctx, change{operation: AuditSavePolicy, added: lines, replaced: true})
+	})
+}
+
+// modelRules converts every "p" and "g" rule in the model into its database form.
+func (a *Adapter) modelRules(model model.Model) []CasbinRule {
+	var lines []CasbinRule
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range model[sec] {
+			for _, rule := range ast.Policy {
+				lines = append(lines, a.savePolicyLine(ptype, rule))
+			}
+		}
 	}
+	return lines
+}
 
-	var batch []CasbinRule
+// replaceRules empties every rule collection and inserts lines in batches, setting their keys.
+// With soft delete enabled, removed rules are kept unless one of lines reuses their key.
+func (a *Adapter) replaceRules(ctx context.Context, lines []CasbinRule) error {
+	const batchSize = 1000
 
-	// Flush the current batch to database
-	flushBatch := func() error {
-		if len(batch) == 0 {
-			return nil
+	// Clear everything out first
+	keys := []string{}
+	for _, line := range lines {
+		if line.Key != "" {
+			keys = append(keys, line.Key)
 		}
-		_, err := a.collection.CreateDocuments(ctx, batch)
-		if err != nil {
+	}
+	for _, name := range a.routing.names() {
+		if err := a.clearCollection(ctx, name, keys); err != nil {
 			return err
 		}
-		batch = batch[:0] // Reset batch
-		return nil
 	}
 
-	// Collect and batch "p" type rules (permissions)
-	for ptype, ast := range model["p"] {
-		for _, rule := range ast.Policy {
-			batch = append(batch, a.savePolicyLine(ptype, rule))
-			if len(batch) >= batchSize {
-				if err := flushBatch(); err != nil {
-					return err
-				}
-			}
+	for start := 0; start < len(lines); start += batchSize {
+		end := min(start+batchSize, len(lines))
+		if err := a.createRules(ctx, lines[start:end]); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	// Collect and batch "g" type rules (roles/groups)
-	for ptype, ast := range model["g"] {
-		for _, rule := range ast.Policy {
-			batch = append(batch, a.savePolicyLine(ptype, rule))
-			if len(batch) >= batchSize {
-				if err := flushBatch(); err != nil {
-					return err
-				}
-			}
-		}
+// clearCollection removes every rule from the named collection. With soft delete enabled,
+// removed rules are kept unless their key is in keys.
+func (a *Adapter) clearCollection(ctx context.Context, name string, keys []string) error {
+	if !a.softDelete {
+		return a.withRetry(ctx, func() error {
+			return a.collections[name].Truncate(ctx)
+		})
 	}
 
-	// Flush any remaining rules
-	return flushBatch()
+	cursor, err := a.query(ctx, "FOR doc IN @@collection FILTER "+notDeleted+" || doc._key IN @keys REMOVE doc IN @@collection", map[string]interface{}{
+		"@collection": name,
+		"keys":        keys,
+	})
+	if err != nil {
+		return err
+	}
+	_ = cursor.Close()
+	return nil
 }
 
 // savePolicyLine converts a Casbin rule into a database-friendly format.
@@ -427,10 +548,22 @@ func (a *Adapter) AddPolicy(sec string, ptype string, rule []string) error {
 }
 
 // AddPolicyCtx is like AddPolicy but with context support.
-func (a *Adapter) AddPolicyCtx(ctx context.Context, sec string, ptype string, rule []string) error {
-	line := a.savePolicyLine(ptype, rule)
-	_, err := a.collection.CreateDocument(ctx, line)
-	return err
+func (a *Adapter) AddPolicyCtx(ctx context.Context, sec string, ptype string, rule []string) (err error) {
+	ctx, op := a.startWrite(ctx, "AddPolicy", ruleAttrs(sec, ptype, 1)...)
+	defer func() { op.end(err) }()
+
+	return a.addRules(ctx, []CasbinRule{a.savePolicyLine(ptype, rule)})
+}
+
+// addRules inserts lines with their creation metadata and records the change.
+func (a *Adapter) addRules(ctx context.Context, lines []CasbinRule) error {
+	stampCreated(ctx, lines)
+	return a.recorded(ctx, func(tx *Adapter) error {
+		if err := tx.createRules(ctx, lines); err != nil {
+			return err
+		}
+		return tx.record(ctx, change{operation: AuditAddPolicy, added: lines})
+	})
 }
 
 // RemovePolicy removes a single policy rule from the database.
@@ -441,45 +574,35 @@ func (a *Adapter) RemovePolicy(sec string, ptype string, rule []string) error {
 // RemovePolicyCtx is like RemovePolicy but with context support.
 // It builds a query to match the exact rule and removes it.
 func (a *Adapter) RemovePolicyCtx(ctx context.Context, sec string, ptype string, rule []string) error {
-	line := a.savePolicyLine(ptype, rule)
-	query := "FOR doc IN @@collection FILTER doc.ptype == @ptype"
-	bindVars := map[string]interface{}{
-		"@collection": a.collectionName,
-		"ptype":       line.Ptype,
-	}
+	_, err := a.RemovePolicyCount(ctx, sec, ptype, rule)
+	return err
+}
 
-	// Build up the query dynamically based on which fields have values
-	if line.V0 != "" {
-		query += " && doc.v0 == @v0"
-		bindVars["v0"] = line.V0
-	}
-	if line.V1 != "" {
-		query += " && doc.v1 == @v1"
-		bindVars["v1"] = line.V1
-	}
-	if line.V2 != "" {
-		query += " && doc.v2 == @v2"
-		bindVars["v2"] = line.V2
-	}
-	if line.V3 != "" {
-		query += " && doc.v3 == @v3"
-		bindVars["v3"] = line.V3
-	}
-	if line.V4 != "" {
-		query += " && doc.v4 == @v4"
-		bindVars["v4"] = line.V4
-	}
-	if line.V5 != "" {
-		query += " && doc.v5 == @v5"
-		bindVars["v5"] = line.V5
+// RemovePolicyCount removes a single policy rule and reports how many documents were deleted.
+// With WithErrOnNoMatch enabled it returns ErrRuleNotFound when nothing matched.
+func (a *Adapter) RemovePolicyCount(ctx context.Context, sec string, ptype string, rule []string) (count int64, err error) {
+	ctx, op := a.startWrite(ctx, "RemovePolicy", ruleAttrs(sec, ptype, 1)...)
+	defer func() { op.end(err) }()
+
+	conditions, bindVars, err := a.mapping.ruleConditions(a.savePolicyLine(ptype, rule))
+	if err != nil {
+		return 0, err
 	}
+	_, count, err = a.removeRules(ctx, AuditRemovePolicy, []string{a.routing.collectionFor(ptype)}, conditions, bindVars, false)
+	return count, err
+}
 
-	query += " REMOVE doc IN @@collection"
+// RemovePolicyReturning removes a single policy rule and returns the documents that were deleted.
+func (a *Adapter) RemovePolicyReturning(ctx context.Context, sec string, ptype string, rule []string) (removed []CasbinRule, err error) {
+	ctx, op := a.startWrite(ctx, "RemovePolicy", ruleAttrs(sec, ptype, 1)...)
+	defer func() { op.end(err) }()
 
-	_, err := a.db.Query(ctx, query, &arangodb.QueryOptions{
-		BindVars: bindVars,
-	})
-	return err
+	conditions, bindVars, err := a.mapping.ruleConditions(a.savePolicyLine(ptype, rule))
+	if err != nil {
+		return nil, err
+	}
+	removed, _, err = a.removeRules(ctx, AuditRemovePolicy, []string{a.routing.collectionFor(ptype)}, conditions, bindVars, true)
+	return removed, err
 }
 
 // AddPolicies adds multiple policy rules at once.
@@ -488,13 +611,15 @@ func (a *Adapter) AddPolicies(sec string, ptype string, rules [][]string) error
 }
 
 // AddPoliciesCtx adds multiple policy rules with context support.
-func (a *Adapter) AddPoliciesCtx(ctx context.Context, sec string, ptype string, rules [][]string) error {
+func (a *Adapter) AddPoliciesCtx(ctx context.Context, sec string, ptype string, rules [][]string) (err error) {
+	ctx, op := a.startWrite(ctx, "AddPolicies", ruleAttrs(sec, ptype, len(rules))...)
+	defer func() { op.end(err) }()
+
 	var lines []CasbinRule
 	for _, rule := range rules {
 		lines = append(lines, a.savePolicyLine(ptype, rule))
 	}
-	_, err := a.collection.CreateDocuments(ctx, lines)
-	return err
+	return a.addRules(ctx, lines)
 }
 
 // RemovePolicies removes multiple policy rules at once.
@@ -503,14 +628,20 @@ func (a *Adapter) RemovePolicies(sec string, ptype string, rules [][]string) err
 }
 
 // RemovePoliciesCtx removes multiple policy rules with context support.
-func (a *Adapter) RemovePoliciesCtx(ctx context.Context, sec string, ptype string, rules [][]string) error {
-	for _, rule := range rules {
-		err := a.RemovePolicyCtx(ctx, sec, ptype, rule)
-		if err != nil {
-			return err
+func (a *Adapter) RemovePoliciesCtx(ctx context.Context, sec string, ptype string, rules [][]string) (err error) {
+	ctx, op := a.startWrite(ctx, "RemovePolicies", ruleAttrs(sec, ptype, len(rules))...)
+	defer func() { op.end(err) }()
+
+	// Remove them all in one transaction when recording changes, so no record goes missing
+	return a.recorded(ctx, func(tx *Adapter) error {
+		for _, rule := range rules {
+			err := tx.RemovePolicyCtx(ctx, sec, ptype, rule)
+			if err != nil {
+				return err
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // RemoveFilteredPolicy removes policies that match a partial filter.
@@ -520,118 +651,219 @@ func (a *Adapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int,
 
 // RemoveFilteredPolicyCtx is like RemoveFilteredPolicy but with context support.
 func (a *Adapter) RemoveFilteredPolicyCtx(ctx context.Context, sec string, ptype string, fieldIndex int, fieldValues ...string) error {
-	query := "FOR doc IN @@collection FILTER doc.ptype == @ptype"
-	bindVars := map[string]interface{}{
-		"@collection": a.collectionName,
-		"ptype":       ptype,
-	}
+	_, err := a.RemoveFilteredPolicyCount(ctx, sec, ptype, fieldIndex, fieldValues...)
+	return err
+}
 
-	// The logic here maps the field values to the right V fields based on the starting index
-	if fieldIndex <= 0 && 0 < fieldIndex+len(fieldValues) {
-		query += " && doc.v0 == @v0"
-		bindVars["v0"] = fieldValues[0-fieldIndex]
-	}
-	if fieldIndex <= 1 && 1 < fieldIndex+len(fieldValues) {
-		query += " && doc.v1 == @v1"
-		bindVars["v1"] = fieldValues[1-fieldIndex]
-	}
-	if fieldIndex <= 2 && 2 < fieldIndex+len(fieldValues) {
-		query += " && doc.v2 == @v2"
-		bindVars["v2"] = fieldValues[2-fieldIndex]
+// RemoveFilteredPolicyCount removes policies that match a partial filter and reports how many were deleted.
+// With WithErrOnNoMatch enabled it returns ErrRuleNotFound when nothing matched.
+func (a *Adapter) RemoveFilteredPolicyCount(ctx context.Context, sec string, ptype string, fieldIndex int, fieldValues ...string) (count int64, err error) {
+	ctx, op := a.startWrite(ctx, "RemoveFilteredPolicy", attrSection.String(sec), attrPtype.String(ptype))
+	defer func() {
+		op.setRuleCount(int(count))
+		op.end(err)
+	}()
+
+	conditions, bindVars, err := a.mapping.filteredConditions(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return 0, err
 	}
-	if fieldIndex <= 3 && 3 < fieldIndex+len(fieldValues) {
-		query += " && doc.v3 == @v3"
-		bindVars["v3"] = fieldValues[3-fieldIndex]
+	_, count, err = a.removeRules(ctx, AuditRemoveFilteredPolicy, []string{a.routing.collectionFor(ptype)}, conditions, bindVars, false)
+	return count, err
+}
+
+// RemoveFilteredPolicyReturning removes policies that match a partial filter and returns the deleted documents.
+func (a *Adapter) RemoveFilteredPolicyReturning(ctx context.Context, sec string, ptype string, fieldIndex int, fieldValues ...string) (removed []CasbinRule, err error) {
+	ctx, op := a.startWrite(ctx, "RemoveFilteredPolicy", attrSection.String(sec), attrPtype.String(ptype))
+	defer func() {
+		op.setRuleCount(len(removed))
+		op.end(err)
+	}()
+
+	conditions, bindVars, err := a.mapping.filteredConditions(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return nil, err
 	}
-	if fieldIndex <= 4 && 4 < fieldIndex+len(fieldValues) {
-		query += " && doc.v4 == @v4"
-		bindVars["v4"] = fieldValues[4-fieldIndex]
+	removed, _, err = a.removeRules(ctx, AuditRemoveFilteredPolicy, []string{a.routing.collectionFor(ptype)}, conditions, bindVars, true)
+	return removed, err
+}
+
+// removeRules deletes every document matching conditions in the named collections and records
+// the change under operation. With soft delete enabled, documents are flagged with deletedAt and
+// deletedBy instead. The number of removed documents comes from the cursors' writesExecuted
+// statistic. When returnOld is set, the removed documents are read back from the cursors too.
+func (a *Adapter) removeRules(ctx context.Context, operation string, names []string, conditions string, bindVars map[string]interface{}, returnOld bool) (removed []CasbinRule, count int64, err error) {
+	err = a.recorded(ctx, func(tx *Adapter) error {
+		// Start over if the transaction is retried
+		removed, count = nil, 0
+
+		// Recording needs the removed rules even if the caller doesn't
+		readOld := returnOld || tx.recording()
+
+		query := "FOR doc IN @@collection FILTER (" + conditions + ") && " + notDeleted
+		if tx.softDelete {
+			query += " UPDATE doc WITH { deletedAt: @deletedAt, deletedBy: @deletedBy } IN @@collection"
+			bindVars["deletedAt"] = formatTime(time.Now())
+			bindVars["deletedBy"] = ActorFromContext(ctx)
+		} else {
+			query += " REMOVE doc IN @@collection"
+		}
+		if readOld {
+			query += " RETURN OLD"
+		}
+
+		for _, name := range names {
+			bindVars["@collection"] = name
+			old, written, err := tx.writeQuery(ctx, query, bindVars, readOld)
+			if err != nil {
+				return err
+			}
+			removed = append(removed, old...)
+			count += written
+		}
+
+		if count == 0 && tx.errOnNoMatch {
+			return ErrRuleNotFound
+		}
+
+		return tx.record(ctx, change{operation: operation, removed: removed})
+	})
+	if err != nil {
+		return nil, 0, err
 	}
-	if fieldIndex <= 5 && 5 < fieldIndex+len(fieldValues) {
-		query += " && doc.v5 == @v5"
-		bindVars["v5"] = fieldValues[5-fieldIndex]
+
+	if !returnOld {
+		removed = nil
 	}
+	return removed, count, nil
+}
 
-	query += " REMOVE doc IN @@collection"
+// writeQuery runs a query that writes documents and reports how many it wrote. When readOld
+// is set, it also reads back the old documents the query returns.
+func (a *Adapter) writeQuery(ctx context.Context, query string, bindVars map[string]interface{}, readOld bool) (removed []CasbinRule, count int64, err error) {
+	cursor, err := a.query(ctx, query, bindVars)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
 
-	_, err := a.db.Query(ctx, query, &arangodb.QueryOptions{
-		BindVars: bindVars,
-	})
-	return err
+	for readOld && cursor.HasMore() {
+		rule, err := a.readRule(ctx, cursor)
+		if err != nil {
+			return nil, 0, err
+		}
+		removed = append(removed, rule)
+	}
+	return removed, int64(cursor.Statistics().WritesExecutedInt), nil
 }
 
 // UpdatePolicy replaces an old policy rule with a new one.
 func (a *Adapter) UpdatePolicy(sec string, ptype string, oldRule, newPolicy []string) error {
+	return a.UpdatePolicyCtx(context.Background(), sec, ptype, oldRule, newPolicy)
+}
+
+// UpdatePolicyCtx is like UpdatePolicy but with context support.
+func (a *Adapter) UpdatePolicyCtx(ctx context.Context, sec string, ptype string, oldRule, newPolicy []string) (err error) {
+	ctx, op := a.startOperation(ctx, "UpdatePolicy", ruleAttrs(sec, ptype, 1)...)
+	defer func() { op.end(err) }()
+
 	oldLine := a.savePolicyLine(ptype, oldRule)
 	newLine := a.savePolicyLine(ptype, newPolicy)
 
-	query := "FOR doc IN @@collection FILTER doc.ptype == @ptype"
-	bindVars := map[string]interface{}{
-		"@collection": a.collectionName,
-		"ptype":       oldLine.Ptype,
+	updates, newBindVars, err := a.mapping.updateAttributes(newLine)
+	if err != nil {
+		return err
 	}
 
-	// Match the old rule
-	if oldLine.V0 != "" {
-		query += " && doc.v0 == @v0"
-		bindVars["v0"] = oldLine.V0
-	}
-	if oldLine.V1 != "" {
-		query += " && doc.v1 == @v1"
-		bindVars["v1"] = oldLine.V1
-	}
-	if oldLine.V2 != "" {
-		query += " && doc.v2 == @v2"
-		bindVars["v2"] = oldLine.V2
-	}
-	if oldLine.V3 != "" {
-		query += " && doc.v3 == @v3"
-		bindVars["v3"] = oldLine.V3
-	}
-	if oldLine.V4 != "" {
-		query += " && doc.v4 == @v4"
-		bindVars["v4"] = oldLine.V4
-	}
-	if oldLine.V5 != "" {
-		query += " && doc.v5 == @v5"
-		bindVars["v5"] = oldLine.V5
-	}
+	return a.recorded(ctx, func(tx *Adapter) error {
+		conditions, bindVars, err := tx.mapping.ruleConditions(oldLine)
+		if err != nil {
+			return err
+		}
+		bindVars["@collection"] = tx.routing.collectionFor(ptype)
+		query := "FOR doc IN @@collection FILTER " + conditions + " && " + notDeleted
 
-	// Update it with the new values
-	query += " UPDATE doc WITH { ptype: @new_ptype, v0: @new_v0, v1: @new_v1, v2: @new_v2, v3: @new_v3, v4: @new_v4, v5: @new_v5 } IN @@collection"
+		// Update it with the new values
+		query += " UPDATE doc WITH { " + updates + ", updatedAt: @updatedAt } IN @@collection"
+		if tx.recording() {
+			query += " RETURN { old: OLD, new: NEW }"
+		}
 
-	bindVars["new_ptype"] = newLine.Ptype
-	bindVars["new_v0"] = newLine.V0
-	bindVars["new_v1"] = newLine.V1
-	bindVars["new_v2"] = newLine.V2
-	bindVars["new_v3"] = newLine.V3
-	bindVars["new_v4"] = newLine.V4
-	bindVars["new_v5"] = newLine.V5
+		for name, value := range newBindVars {
+			bindVars[name] = value
+		}
+		bindVars["updatedAt"] = formatTime(time.Now())
 
-	_, err := a.db.Query(context.Background(), query, &arangodb.QueryOptions{
-		BindVars: bindVars,
+		cursor, err := tx.query(ctx, query, bindVars)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = cursor.Close()
+		}()
+
+		c := change{operation: AuditUpdatePolicy}
+		for tx.recording() && cursor.HasMore() {
+			var updated struct {
+				Old json.RawMessage `json:"old"`
+				New json.RawMessage `json:"new"`
+			}
+			if _, err := cursor.ReadDocument(ctx, &updated); err != nil {
+				return err
+			}
+			old, err := tx.mapping.decode(updated.Old)
+			if err != nil {
+				return err
+			}
+			rule, err := tx.mapping.decode(updated.New)
+			if err != nil {
+				return err
+			}
+			c.removed = append(c.removed, old)
+			c.added = append(c.added, rule)
+		}
+		return tx.record(ctx, c)
 	})
-	return err
 }
 
 // UpdatePolicies updates multiple policy rules at once.
 func (a *Adapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
-	for i, oldRule := range oldRules {
-		err := a.UpdatePolicy(sec, ptype, oldRule, newRules[i])
-		if err != nil {
-			return err
+	return a.UpdatePoliciesCtx(context.Background(), sec, ptype, oldRules, newRules)
+}
+
+// UpdatePoliciesCtx is like UpdatePolicies but with context support.
+func (a *Adapter) UpdatePoliciesCtx(ctx context.Context, sec string, ptype string, oldRules, newRules [][]string) (err error) {
+	ctx, op := a.startOperation(ctx, "UpdatePolicies", ruleAttrs(sec, ptype, len(oldRules))...)
+	defer func() { op.end(err) }()
+
+	return a.recorded(ctx, func(tx *Adapter) error {
+		for i, oldRule := range oldRules {
+			err := tx.UpdatePolicyCtx(ctx, sec, ptype, oldRule, newRules[i])
+			if err != nil {
+				return err
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // UpdateFilteredPolicies updates policies that match a filter.
 // Right now it just adds the new policies - doesn't remove old ones.
 func (a *Adapter) UpdateFilteredPolicies(sec string, ptype string, newPolicies [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	return a.UpdateFilteredPoliciesCtx(context.Background(), sec, ptype, newPolicies, fieldIndex, fieldValues...)
+}
+
+// UpdateFilteredPoliciesCtx is like UpdateFilteredPolicies but with context support.
+func (a *Adapter) UpdateFilteredPoliciesCtx(ctx context.Context, sec string, ptype string, newPolicies [][]string, fieldIndex int, fieldValues ...string) (_ [][]string, err error) {
+	ctx, op := a.startWrite(ctx, "UpdateFilteredPolicies", ruleAttrs(sec, ptype, len(newPolicies))...)
+	defer func() { op.end(err) }()
+
 	oldPolicies := make([][]string, 0)
 
 	for _, newPolicy := range newPolicies {
-		err := a.AddPolicy(sec, ptype, newPolicy)
+		err := a.AddPolicyCtx(ctx, sec, ptype, newPolicy)
 		if err != nil {
 			return nil, err
 		}
@@ -641,9 +873,11 @@ func (a *Adapter) UpdateFilteredPolicies(sec string, ptype string, newPolicies [
 }
 
 // Close shuts down the adapter.
-// ArangoDB handles connections internally, so this is mostly a no-op.
-// We have it to satisfy the adapter interface.
+// ArangoDB handles connections internally, so this only stops the expiry watcher, if any.
 func (a *Adapter) Close() error {
+	if a.stopExpiryWatcher != nil {
+		a.stopExpiryWatcher()
+	}
 	return nil
 }
 
@@ -651,19 +885,46 @@ func (a *Adapter) Close() error {
 // Useful for transaction handling where we need separate adapter instances.
 func (a *Adapter) Copy() *Adapter {
 	return &Adapter{
-		client:         a.client,
-		db:             a.db,
-		collection:     a.collection,
-		databaseName:   a.databaseName,
-		collectionName: a.collectionName,
-		isFiltered:     a.isFiltered,
-		transactionMu:  a.transactionMu,
+		client:                a.client,
+		db:                    a.db,
+		collection:            a.collection,
+		collections:           a.collections,
+		databaseName:          a.databaseName,
+		collectionName:        a.collectionName,
+		routing:               a.routing,
+		isFiltered:            a.isFiltered,
+		errOnNoMatch:          a.errOnNoMatch,
+		softDelete:            a.softDelete,
+		readOnly:              a.readOnly,
+		lenientLoad:           a.lenientLoad,
+		onSkipped:             a.onSkipped,
+		mapping:               a.mapping,
+		retryPolicy:           a.retryPolicy,
+		tracer:                a.tracer,
+		metrics:               a.metrics,
+		sizeInterval:          a.sizeInterval,
+		sizeRefreshed:         a.sizeRefreshed,
+		logger:                a.logger,
+		logQueries:            a.logQueries,
+		redactFields:          a.redactFields,
+		sortIndexes:           a.sortIndexes,
+		expiry:                a.expiry,
+		expiryIndexed:         a.expiryIndexed,
+		auditCollection:       a.auditCollection,
+		auditCollectionName:   a.auditCollectionName,
+		historyCollection:     a.historyCollection,
+		historyCollectionName: a.historyCollectionName,
+		validationLevel:       a.validationLevel,
+		metaCollection:        a.metaCollection,
+		metaCollectionName:    a.metaCollectionName,
+		stopExpiryWatcher:     a.stopExpiryWatcher,
+		transactionMu:         a.transactionMu,
 	}
 }
 
 // Transaction executes a function within a database transaction.
 // This is the old-style transaction interface for backward compatibility.
-func (a *Adapter) Transaction(e casbin.IEnforcer, fc func(casbin.IEnforcer) error) error {
+func (a *Adapter) Transaction(e casbin.IEnforcer, fc func(casbin.IEnforcer) error) (err error) {
 	// Ensure transaction mutex is initialized
 	if a.transactionMu == nil {
 		a.muInitialize.Do(func() {
@@ -677,30 +938,31 @@ func (a *Adapter) Transaction(e casbin.IEnforcer, fc func(casbin.IEnforcer) erro
 	a.transactionMu.Lock()
 	defer a.transactionMu.Unlock()
 
+	ctx, op := a.startOperation(context.Background(), "Transaction")
+	defer func() { op.end(err) }()
+
+	// Retrying the whole transaction is safe: a failed attempt is aborted
+	// and the model reloaded before fc runs again.
+	if a.retryPolicy == nil {
+		return a.runTransaction(ctx, e, fc)
+	}
+	return a.retryPolicy.do(ctx, a.logger, func() error {
+		return a.runTransaction(ctx, e, fc)
+	})
+}
+
+// runTransaction makes a single attempt at running fc inside a stream transaction.
+func (a *Adapter) runTransaction(ctx context.Context, e casbin.IEnforcer, fc func(casbin.IEnforcer) error) (err error) {
 	// Save original adapter
 	originalAdapter := a.Copy()
 
-	ctx := context.Background()
-
 	// Start ArangoDB streaming transaction
-	tx, err := a.db.BeginTransaction(ctx, arangodb.TransactionCollections{
-		Write: []string{a.collectionName},
-	}, nil)
+	tx, txAdapter, err := a.beginTransaction(ctx)
 	if err != nil {
 		return err
 	}
-
-	// Create transaction adapter
-	txAdapter := &Adapter{
-		client:         a.client,
-		db:             a.db,
-		collection:     a.collection,
-		databaseName:   a.databaseName,
-		collectionName: a.collectionName,
-		isFiltered:     a.isFiltered,
-		transactionMu:  a.transactionMu,
-		transaction:    tx, // Store transaction
-	}
+	trace.SpanFromContext(ctx).AddEvent("transaction started", trace.WithAttributes(attrTransactionID.String(string(tx.ID()))))
+	a.logger.DebugContext(ctx, "transaction started", slog.String("transaction", string(tx.ID())))
 
 	// Temporarily set transaction adapter
 	e.SetAdapter(txAdapter)
@@ -712,126 +974,242 @@ func (a *Adapter) Transaction(e casbin.IEnforcer, fc func(casbin.IEnforcer) erro
 	e.SetAdapter(originalAdapter)
 
 	if err != nil {
-		// Rollback on error
+		// Rollback on error. ArangoDB may have aborted the transaction already
+		// (e.g. after a write conflict), so keep the original error around.
 		if abortErr := tx.Abort(ctx, nil); abortErr != nil {
-			return abortErr
+			err = errors.Join(err, abortErr)
 		}
+		a.metrics.ObserveTransaction(false)
+		a.logger.WarnContext(ctx, "transaction rolled back",
+			slog.String("transaction", string(tx.ID())),
+			slog.Any("error", err),
+		)
 		// Reload policy to sync in-memory model with database
 		if loadErr := e.LoadPolicy(); loadErr != nil {
-			return loadErr
+			return errors.Join(err, loadErr)
 		}
 		return err
 	}
 
 	// Commit transaction
 	if commitErr := tx.Commit(ctx, nil); commitErr != nil {
+		a.metrics.ObserveTransaction(false)
+		a.logger.WarnContext(ctx, "transaction commit failed",
+			slog.String("transaction", string(tx.ID())),
+			slog.Any("error", commitErr),
+		)
+		// Nothing was written, so bring the model back in line before a retry
+		if loadErr := e.LoadPolicy(); loadErr != nil {
+			return errors.Join(commitErr, loadErr)
+		}
 		return commitErr
 	}
+	a.metrics.ObserveTransaction(true)
+	a.refreshCollectionSize(ctx)
+	a.logger.InfoContext(ctx, "transaction committed", slog.String("transaction", string(tx.ID())))
 
 	return nil
 }
 
 // BeginTransaction starts a new database transaction.
 // Returns a context you can use to commit or rollback.
-func (a *Adapter) BeginTransaction(ctx context.Context) (persist.TransactionContext, error) {
-	// Start ArangoDB streaming transaction
-	tx, err := a.db.BeginTransaction(ctx, arangodb.TransactionCollections{
-		Write: []string{a.collectionName},
-	}, nil)
+func (a *Adapter) BeginTransaction(ctx context.Context) (_ persist.TransactionContext, err error) {
+	spanCtx, op := a.startOperation(ctx, "BeginTransaction")
+	defer func() { op.end(err) }()
+
+	// Start ArangoDB streaming transaction. Nothing has run in it yet, so it's safe to retry.
+	var tx arangodb.Transaction
+	var txAdapter *Adapter
+	err = a.withRetry(spanCtx, func() error {
+		var err error
+		tx, txAdapter, err = a.beginTransaction(spanCtx)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	op.span.SetAttributes(attrTransactionID.String(string(tx.ID())))
+	a.logger.DebugContext(ctx, "transaction started", slog.String("transaction", string(tx.ID())))
 
 	return &ArangoTransactionContext{
-		tx:             tx,
-		ctx:            ctx,
-		adapter:        a,
-		collectionName: a.collectionName,
+		tx:        tx,
+		ctx:       ctx,
+		adapter:   a,
+		txAdapter: txAdapter,
 	}, nil
 }
 
+// beginTransaction starts a stream transaction on every collection the adapter writes to
+// and returns an adapter whose operations run inside it. It makes a single attempt, as
+// callers retry the transaction as a whole; operations inside it are never retried.
+func (a *Adapter) beginTransaction(ctx context.Context) (arangodb.Transaction, *Adapter, error) {
+	tx, err := a.db.BeginTransaction(ctx, arangodb.TransactionCollections{
+		Write: a.writeCollections(),
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txAdapter, err := a.bindTransaction(ctx, tx)
+	if err != nil {
+		_ = tx.Abort(ctx, nil)
+		return nil, nil, err
+	}
+
+	return tx, txAdapter, nil
+}
+
+// writeCollections lists the collections a transaction has to declare for writing.
+func (a *Adapter) writeCollections() []string {
+	names := a.routing.names()
+	if a.auditCollectionName != "" {
+		names = append(names, a.auditCollectionName)
+	}
+	if a.historyCollectionName != "" {
+		names = append(names, a.historyCollectionName)
+	}
+	return names
+}
+
+// bindTransaction returns a copy of the adapter whose collections are bound to tx.
+func (a *Adapter) bindTransaction(ctx context.Context, tx arangodb.Transaction) (*Adapter, error) {
+	txAdapter := a.Copy()
+	txAdapter.transaction = tx
+
+	txAdapter.collections = make(map[string]arangodb.Collection)
+	for _, name := range a.routing.names() {
+		col, err := tx.Collection(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		txAdapter.collections[name] = col
+	}
+	txAdapter.collection = txAdapter.collections[a.collectionName]
+
+	var err error
+
+	if a.auditCollectionName != "" {
+		if txAdapter.auditCollection, err = tx.Collection(ctx, a.auditCollectionName); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.historyCollectionName != "" {
+		if txAdapter.historyCollection, err = tx.Collection(ctx, a.historyCollectionName); err != nil {
+			return nil, err
+		}
+	}
+
+	return txAdapter, nil
+}
+
+// inTransaction runs fn with an adapter bound to a stream transaction and commits it afterwards.
+// If the adapter is already part of a transaction, fn simply joins it.
+func (a *Adapter) inTransaction(ctx context.Context, fn func(tx *Adapter) error) error {
+	if a.transaction != nil {
+		return fn(a)
+	}
+
+	// Retrying is safe here: a failed attempt is aborted as a whole
+	return a.withRetry(ctx, func() error {
+		tx, txAdapter, err := a.beginTransaction(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(txAdapter); err != nil {
+			if abortErr := tx.Abort(ctx, nil); abortErr != nil {
+				return errors.Join(err, abortErr)
+			}
+			return err
+		}
+		return tx.Commit(ctx, nil)
+	})
+}
+
 // ArangoTransactionContext wraps an ArangoDB transaction for Casbin.
 type ArangoTransactionContext struct {
-	tx             arangodb.Transaction
-	ctx            context.Context
-	adapter        *Adapter
-	collectionName string
-	committed      bool
-	rolledBack     bool
+	tx         arangodb.Transaction
+	ctx        context.Context
+	adapter    *Adapter
+	txAdapter  *Adapter // Adapter bound to tx
+	committed  bool
+	rolledBack bool
 }
 
 // Commit commits the database transaction.
-func (atx *ArangoTransactionContext) Commit() error {
+func (atx *ArangoTransactionContext) Commit() (err error) {
+	ctx, op := atx.startOperation("CommitTransaction")
+	defer func() { op.end(err) }()
+
 	if atx.committed || atx.rolledBack {
 		return errors.New("transaction already finished")
 	}
 
-	err := atx.tx.Commit(atx.ctx, nil)
+	err = atx.tx.Commit(ctx, nil)
 	if err == nil {
 		atx.committed = true
+		atx.adapter.metrics.ObserveTransaction(true)
+		atx.adapter.refreshCollectionSize(ctx)
+		atx.adapter.logger.InfoContext(ctx, "transaction committed", slog.String("transaction", string(atx.tx.ID())))
+	} else {
+		atx.adapter.logger.WarnContext(ctx, "transaction commit failed",
+			slog.String("transaction", string(atx.tx.ID())),
+			slog.Any("error", err),
+		)
 	}
 	return err
 }
 
 // Rollback rolls back the database transaction.
-func (atx *ArangoTransactionContext) Rollback() error {
+func (atx *ArangoTransactionContext) Rollback() (err error) {
+	ctx, op := atx.startOperation("RollbackTransaction")
+	defer func() { op.end(err) }()
+
 	if atx.committed || atx.rolledBack {
 		return errors.New("transaction already finished")
 	}
 
-	err := atx.tx.Abort(atx.ctx, nil)
+	err = atx.tx.Abort(ctx, nil)
 	if err == nil {
 		atx.rolledBack = true
+		atx.adapter.metrics.ObserveTransaction(false)
+		atx.adapter.logger.InfoContext(ctx, "transaction rolled back", slog.String("transaction", string(atx.tx.ID())))
 	}
 	return err
 }
 
+// startOperation starts an operation under the context passed to BeginTransaction.
+func (atx *ArangoTransactionContext) startOperation(name string) (context.Context, *operation) {
+	return atx.adapter.startOperation(atx.ctx, name, attrTransactionID.String(string(atx.tx.ID())))
+}
+
 // GetAdapter returns an adapter that uses this transaction.
 // Any policies you add/remove through it will be part of the transaction.
 func (atx *ArangoTransactionContext) GetAdapter() persist.Adapter {
-	return &Adapter{
-		client:         atx.adapter.client,
-		db:             atx.adapter.db,
-		collection:     atx.adapter.collection,
-		databaseName:   atx.adapter.databaseName,
-		collectionName: atx.collectionName,
-		isFiltered:     atx.adapter.isFiltered,
-		transaction:    atx.tx, // Use transaction
-	}
+	return atx.txAdapter
 }
 
-// Preview checks which rules are valid for the model.
-// Filters out rules that don't match, so you don't get partial load failures.
+// Preview narrows rules down to the ones the model already holds, keeping their order.
+// It fails without changing rules if one can't be held by the model at all, e.g. because
+// of an unknown ptype or the wrong number of values. Use ValidateRules for a full report.
 func (a *Adapter) Preview(rules *[]CasbinRule, model model.Model) error {
-	j := 0
-	for i, rule := range *rules {
-		// Build policy array
-		r := []string{rule.Ptype, rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5}
-
-		// Trim trailing empty fields
-		index := len(r) - 1
-		for r[index] == "" {
-			index--
+	var kept []CasbinRule
+	for _, rule := range *rules {
+		sec, ok := ptypeSection(model, rule.Ptype)
+		if !ok {
+			return fmt.Errorf("ptype %q isn't defined in the model", rule.Ptype)
 		}
-		p := r[:index+1]
 
-		key := p[0]
-		sec := key[:1]
-
-		// Check if this policy is valid for the model
-		ok, err := model.HasPolicyEx(sec, key, p[1:])
+		ok, err := model.HasPolicyEx(sec, rule.Ptype, rule.policyArray()[1:])
 		if err != nil {
 			return err
 		}
-
-		// Keep only valid rules
 		if ok {
-			(*rules)[j], (*rules)[i] = rule, (*rules)[j]
-			j++
+			kept = append(kept, rule)
 		}
 	}
 
-	// Truncate to valid rules only
-	*rules = (*rules)[j:]
+	*rules = kept
 	return nil
 }
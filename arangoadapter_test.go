@@ -11,13 +11,13 @@ import (
 
 // Helper function to create a test adapter
 // You'll need a running ArangoDB instance for these tests
-func setupTestAdapter(t *testing.T) *Adapter {
-	adapter, err := NewAdapter(
+func setupTestAdapter(t *testing.T, opts ...Option) *Adapter {
+	adapter, err := NewAdapter(append([]Option{
 		WithEndpoints("http://localhost:8529"),
 		WithAuthentication("root", ""),
 		WithDatabase("casbin_test"),
 		WithCollection("casbin_rule_test"),
-	)
+	}, opts...)...)
 	if err != nil {
 		t.Skipf("Could not connect to ArangoDB: %v (skipping test)", err)
 	}
@@ -34,6 +34,17 @@ func teardownTestAdapter(t *testing.T, adapter *Adapter) {
 	}
 }
 
+// newTestModel returns a basic ACL model with p and g sections.
+func newTestModel() model.Model {
+	m := model.NewModel()
+	m.AddDef("r", "r", "sub, obj, act")
+	m.AddDef("p", "p", "sub, obj, act")
+	m.AddDef("g", "g", "_, _")
+	m.AddDef("e", "e", "some(where (p.eft == allow))")
+	m.AddDef("m", "m", "r.sub == p.sub && r.obj == p.obj && r.act == p.act")
+	return m
+}
+
 func TestNewAdapter(t *testing.T) {
 	adapter := setupTestAdapter(t)
 	defer teardownTestAdapter(t, adapter)
@@ -235,6 +246,77 @@ func TestRemoveFilteredPolicy(t *testing.T) {
 	}
 }
 
+func TestRemovePolicyCount(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := context.Background()
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+
+	count, err := adapter.RemovePolicyCount(ctx, "p", "p", []string{"alice", "data1", "read"})
+	if err != nil {
+		t.Fatalf("Failed to remove policy: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 removed document, got %d", count)
+	}
+
+	// Removing it again shouldn't match anything
+	count, err = adapter.RemovePolicyCount(ctx, "p", "p", []string{"alice", "data1", "read"})
+	if err != nil {
+		t.Fatalf("Failed to remove missing policy: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected 0 removed documents, got %d", count)
+	}
+}
+
+func TestRemoveFilteredPolicyReturning(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+
+	rules := [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data2", "read"},
+		{"bob", "data1", "write"},
+	}
+	_ = adapter.AddPolicies("p", "p", rules)
+
+	removed, err := adapter.RemoveFilteredPolicyReturning(context.Background(), "p", "p", 0, "alice")
+	if err != nil {
+		t.Fatalf("Failed to remove filtered policy: %v", err)
+	}
+
+	if len(removed) != 2 {
+		t.Fatalf("Expected 2 removed rules, got %d", len(removed))
+	}
+	for _, rule := range removed {
+		if rule.V0 != "alice" || rule.Key == "" {
+			t.Errorf("Unexpected removed rule: %+v", rule)
+		}
+	}
+}
+
+func TestErrOnNoMatch(t *testing.T) {
+	adapter := setupTestAdapter(t, WithErrOnNoMatch())
+	defer teardownTestAdapter(t, adapter)
+
+	err := adapter.RemovePolicy("p", "p", []string{"nobody", "data1", "read"})
+	if !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound, got %v", err)
+	}
+
+	err = adapter.RemoveFilteredPolicy("p", "p", 0, "nobody")
+	if !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound, got %v", err)
+	}
+
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	if err := adapter.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Errorf("Removing an existing rule should succeed: %v", err)
+	}
+}
+
 func TestUpdatePolicy(t *testing.T) {
 	adapter := setupTestAdapter(t)
 	defer teardownTestAdapter(t, adapter)
@@ -602,6 +684,21 @@ func TestPreview(t *testing.T) {
 	}
 }
 
+func TestPreviewInvalidRules(t *testing.T) {
+	adapter := &Adapter{}
+	m := newTestModel()
+
+	for _, rule := range []CasbinRule{{}, {Ptype: "p"}, {Ptype: "p9", V0: "alice"}} {
+		rules := []CasbinRule{{Ptype: "p", V0: "alice", V1: "data1", V2: "read"}, rule}
+		if err := adapter.Preview(&rules, m); err == nil {
+			t.Errorf("Expected an error for %+v", rule)
+		}
+		if len(rules) != 2 {
+			t.Errorf("Rules shouldn't change on errors, got %+v", rules)
+		}
+	}
+}
+
 func TestClose(t *testing.T) {
 	adapter := setupTestAdapter(t)
 	defer teardownTestAdapter(t, adapter)
@@ -0,0 +1,296 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrAuditDisabled is returned by AuditTrail when the adapter was created without WithAudit.
+var ErrAuditDisabled = errors.New("audit log is not enabled")
+
+// timeFormat is how timestamps are stored: ISO 8601 in UTC with fixed millisecond
+// precision, so they sort correctly as strings and ArangoDB's date functions understand them.
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+// formatTime converts t into the stored timestamp format.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// Audit operations recorded in AuditEntry.Operation.
+const (
+	AuditAddPolicy            = "AddPolicy"
+	AuditRemovePolicy         = "RemovePolicy"
+	AuditRemoveFilteredPolicy = "RemoveFilteredPolicy"
+	AuditUpdatePolicy         = "UpdatePolicy"
+	AuditSavePolicy           = "SavePolicy"
+	AuditRestorePolicy        = "RestorePolicy"
+	AuditExpirePolicy         = "ExpirePolicy"
+	AuditRestoreRemovedPolicy = "RestoreRemovedPolicy"
+	AuditImportPolicy         = "ImportPolicy"
+	AuditMigratePolicy        = "MigratePolicy"
+	AuditRepairPolicy         = "RepairPolicy"
+)
+
+// AuditEntry records a single change to the policy collection.
+// Add entries only have NewRule, removals only OldRule, and updates both.
+// SavePolicy, RestorePolicyAt and replacing imports replace everything at once, so they're recorded
+// as one entry with RuleCount set.
+type AuditEntry struct {
+	Key       string      `json:"_key,omitempty"`
+	Operation string      `json:"operation"`
+	Ptype     string      `json:"ptype,omitempty"`
+	OldRule   *CasbinRule `json:"oldRule,omitempty"`
+	NewRule   *CasbinRule `json:"newRule,omitempty"`
+	RuleCount int         `json:"ruleCount,omitempty"` // Number of rules written when everything was replaced
+	Actor     string      `json:"actor,omitempty"`     // Taken from the context, see WithActor
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// storedAuditEntry is an AuditEntry as it's stored. Its rules are encoded like in the rule
+// collections, so field mappings apply and encrypted values stay encrypted.
+type storedAuditEntry struct {
+	Key       string          `json:"_key,omitempty"`
+	Operation string          `json:"operation"`
+	Ptype     string          `json:"ptype,omitempty"`
+	OldRule   json.RawMessage `json:"oldRule,omitempty"`
+	NewRule   json.RawMessage `json:"newRule,omitempty"`
+	RuleCount int             `json:"ruleCount,omitempty"`
+	Actor     string          `json:"actor,omitempty"`
+	Timestamp string          `json:"timestamp"`
+}
+
+// actorKey is the context key holding the actor ID.
+type actorKey struct{}
+
+// WithActor returns a context that attributes policy changes to actor in the audit log.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor ID set with WithActor, or an empty string.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// AuditQuery selects entries from the audit log. Zero fields match everything.
+type AuditQuery struct {
+	Subject string    // Matches v0 of the old or new rule
+	Object  string    // Matches v1 of the old or new rule
+	Ptype   string    // Matches the rule's ptype
+	Actor   string    // Matches the actor that made the change
+	From    time.Time // Only entries at or after this time
+	To      time.Time // Only entries before this time
+	Limit   int       // Maximum number of entries, 0 means no limit
+}
+
+// ensureAuditCollection gets or creates the audit collection.
+// An empty name defaults to the policy collection name with an "_audit" suffix.
+func (a *Adapter) ensureAuditCollection(name string) error {
+	if name == "" {
+		name = a.collectionName + "_audit"
+	}
+
+	ctx := context.Background()
+	col, err := a.ensureCollection(ctx, name, nil)
+	if err != nil {
+		return err
+	}
+
+	// Most lookups are by time range, optionally narrowed down by subject
+	if !a.readOnly {
+		if _, _, err := col.EnsurePersistentIndex(ctx, []string{"timestamp"}, nil); err != nil {
+			return err
+		}
+	}
+
+	a.auditCollection = col
+	a.auditCollectionName = name
+	return nil
+}
+
+// audit writes entries to the audit collection, stamped with the current time and the
+// context's actor. It does nothing when auditing is disabled.
+func (a *Adapter) audit(ctx context.Context, entries ...AuditEntry) error {
+	if a.auditCollection == nil || len(entries) == 0 {
+		return nil
+	}
+
+	now := formatTime(time.Now())
+	actor := ActorFromContext(ctx)
+	docs := make([]storedAuditEntry, len(entries))
+	for i, entry := range entries {
+		if entry.Ptype == "" {
+			if entry.NewRule != nil {
+				entry.Ptype = entry.NewRule.Ptype
+			} else if entry.OldRule != nil {
+				entry.Ptype = entry.OldRule.Ptype
+			}
+		}
+
+		doc := storedAuditEntry{
+			Operation: entry.Operation,
+			Ptype:     entry.Ptype,
+			RuleCount: entry.RuleCount,
+			Actor:     actor,
+			Timestamp: now,
+		}
+		var err error
+		if doc.OldRule, err = a.encodeAuditRule(entry.OldRule); err != nil {
+			return err
+		}
+		if doc.NewRule, err = a.encodeAuditRule(entry.NewRule); err != nil {
+			return err
+		}
+		docs[i] = doc
+	}
+
+	_, err := a.auditCollection.CreateDocuments(ctx, docs)
+	return err
+}
+
+// encodeAuditRule encodes a rule of an audit entry, if there is one.
+func (a *Adapter) encodeAuditRule(rule *CasbinRule) (json.RawMessage, error) {
+	if rule == nil {
+		return nil, nil
+	}
+	doc, err := a.mapping.encode(*rule)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// decodeAuditRule decodes a stored rule of an audit entry, if there is one.
+func (a *Adapter) decodeAuditRule(raw json.RawMessage) (*CasbinRule, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	rule, err := a.mapping.decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// auditValueCondition matches entries whose old or new rule has value at position i,
+// in any form it could be stored in.
+func (a *Adapter) auditValueCondition(i int, value string, bindVars map[string]interface{}) (string, error) {
+	stored, err := a.mapping.encodeAny(i, []string{value})
+	if err != nil {
+		return "", err
+	}
+	bindVars[fieldNames[i]] = stored
+	return "LENGTH((FOR doc IN [entry.oldRule, entry.newRule] FILTER doc != null && " +
+		a.mapping.anyFieldExpr(i) + " IN @" + fieldNames[i] + " RETURN 1)) > 0", nil
+}
+
+// AuditTrail returns audit entries matching q, oldest first.
+// It requires the adapter to be created with WithAudit.
+func (a *Adapter) AuditTrail(ctx context.Context, q AuditQuery) (_ []AuditEntry, err error) {
+	ctx, op := a.startOperation(ctx, "AuditTrail")
+	defer func() { op.end(err) }()
+
+	if a.auditCollectionName == "" {
+		return nil, ErrAuditDisabled
+	}
+
+	bindVars := map[string]interface{}{
+		"@audit": a.auditCollectionName,
+	}
+	var conditions []string
+	for i, value := range []string{q.Subject, q.Object} {
+		if value == "" {
+			continue
+		}
+		condition, err := a.auditValueCondition(i, value, bindVars)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	if q.Ptype != "" {
+		conditions = append(conditions, "entry.ptype == @ptype")
+		bindVars["ptype"] = q.Ptype
+	}
+	if q.Actor != "" {
+		conditions = append(conditions, "entry.actor == @actor")
+		bindVars["actor"] = q.Actor
+	}
+	if !q.From.IsZero() {
+		conditions = append(conditions, "entry.timestamp >= @from")
+		bindVars["from"] = formatTime(q.From)
+	}
+	if !q.To.IsZero() {
+		conditions = append(conditions, "entry.timestamp < @to")
+		bindVars["to"] = formatTime(q.To)
+	}
+
+	query := "FOR entry IN @@audit"
+	if len(conditions) > 0 {
+		query += " FILTER " + strings.Join(conditions, " AND ")
+	}
+	query += " SORT entry.timestamp"
+	if q.Limit > 0 {
+		query += " LIMIT @limit"
+		bindVars["limit"] = q.Limit
+	}
+	query += " RETURN entry"
+
+	cursor, err := a.query(ctx, query, bindVars)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
+
+	var entries []AuditEntry
+	for cursor.HasMore() {
+		var doc storedAuditEntry
+		if _, err := cursor.ReadDocument(ctx, &doc); err != nil {
+			return nil, err
+		}
+
+		entry := AuditEntry{
+			Key:       doc.Key,
+			Operation: doc.Operation,
+			Ptype:     doc.Ptype,
+			RuleCount: doc.RuleCount,
+			Actor:     doc.Actor,
+		}
+		if entry.Timestamp, err = time.Parse(time.RFC3339, doc.Timestamp); err != nil {
+			return nil, err
+		}
+		if entry.OldRule, err = a.decodeAuditRule(doc.OldRule); err != nil {
+			return nil, err
+		}
+		if entry.NewRule, err = a.decodeAuditRule(doc.NewRule); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	op.setRuleCount(len(entries))
+	return entries, nil
+}
+
+// auditRules builds one audit entry per rule for the given operation.
+func auditRules(operation string, oldRules, newRules []CasbinRule) []AuditEntry {
+	var entries []AuditEntry
+	for i := range oldRules {
+		old := oldRules[i]
+		old.Key = ""
+		entries = append(entries, AuditEntry{Operation: operation, OldRule: &old})
+	}
+	for i := range newRules {
+		rule := newRules[i]
+		rule.Key = ""
+		entries = append(entries, AuditEntry{Operation: operation, NewRule: &rule})
+	}
+	return entries
+}
@@ -0,0 +1,68 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestActorFromContext(t *testing.T) {
+	if actor := ActorFromContext(context.Background()); actor != "" {
+		t.Errorf("Expected no actor, got %q", actor)
+	}
+
+	ctx := WithActor(context.Background(), "alice")
+	if actor := ActorFromContext(ctx); actor != "alice" {
+		t.Errorf("Expected actor alice, got %q", actor)
+	}
+}
+
+func TestAuditTrail(t *testing.T) {
+	adapter := setupTestAdapter(t, WithAudit(""))
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := WithActor(context.Background(), "admin")
+	start := time.Now().Add(-time.Second)
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if err := adapter.UpdatePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	if err := adapter.RemovePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Failed to remove policy: %v", err)
+	}
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data2", "read"})
+
+	entries, err := adapter.AuditTrail(context.Background(), AuditQuery{Subject: "alice", From: start})
+	if err != nil {
+		t.Fatalf("Failed to read audit trail: %v", err)
+	}
+
+	expected := []string{AuditAddPolicy, AuditUpdatePolicy, AuditRemovePolicy}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries for alice, got %d", len(expected), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Operation != expected[i] {
+			t.Errorf("Entry %d: expected %s, got %s", i, expected[i], entry.Operation)
+		}
+		if entry.Actor != "admin" {
+			t.Errorf("Entry %d: expected actor admin, got %q", i, entry.Actor)
+		}
+	}
+	if entries[1].OldRule == nil || entries[1].OldRule.V2 != "read" || entries[1].NewRule.V2 != "write" {
+		t.Errorf("Update entry should hold old and new rule: %+v", entries[1])
+	}
+}
+
+func TestAuditTrailDisabled(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+
+	if _, err := adapter.AuditTrail(context.Background(), AuditQuery{}); !errors.Is(err, ErrAuditDisabled) {
+		t.Errorf("Expected ErrAuditDisabled, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package arangoadapter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/arangodb/go-driver/v2/arangodb"
+)
+
+// change describes a write to the policy collection, so it can be recorded in the
+// audit log and the revision history.
+type change struct {
+	operation string
+	removed   []CasbinRule // Rules that are gone, with their document keys
+	added     []CasbinRule // Rules that were written, with their document keys
+	replaced  bool         // The whole collection was replaced
+}
+
+// recording reports whether writes have to be recorded anywhere.
+func (a *Adapter) recording() bool {
+	return a.auditCollection != nil || a.historyCollection != nil
+}
+
+// recorded runs fn so that its changes and their records are written atomically.
+// When nothing is recorded, fn just runs on the adapter itself.
+func (a *Adapter) recorded(ctx context.Context, fn func(tx *Adapter) error) error {
+	if !a.recording() {
+		return fn(a)
+	}
+	return a.inTransaction(ctx, fn)
+}
+
+// record writes c to the audit log and the revision history, whichever are enabled.
+func (a *Adapter) record(ctx context.Context, c change) error {
+	if err := a.audit(ctx, c.auditEntries()...); err != nil {
+		return err
+	}
+	return a.recordHistory(ctx, c)
+}
+
+// auditEntries turns c into audit log entries.
+func (c change) auditEntries() []AuditEntry {
+	switch {
+	case c.replaced:
+		return []AuditEntry{{Operation: c.operation, RuleCount: len(c.added)}}
+	case c.operation == AuditUpdatePolicy:
+		// Updates keep the document, so removed and added line up
+		var entries []AuditEntry
+		for i := range c.removed {
+			old, updated := c.removed[i], c.added[i]
+			old.Key, updated.Key = "", ""
+			entries = append(entries, AuditEntry{Operation: c.operation, OldRule: &old, NewRule: &updated})
+		}
+		return entries
+	default:
+		return auditRules(c.operation, c.removed, c.added)
+	}
+}
+
+// createRules inserts lines into the collections they're routed to and sets each one's Key
+// to the document key it was stored under. Only the requests themselves are retried;
+// a rule ArangoDB rejects fails the whole call.
+func (a *Adapter) createRules(ctx context.Context, lines []CasbinRule) error {
+	// Group the lines by collection, remembering where each one came from
+	var names []string
+	positions := make(map[string][]int)
+	for i, line := range lines {
+		name := a.routing.collectionFor(line.Ptype)
+		if _, ok := positions[name]; !ok {
+			names = append(names, name)
+		}
+		positions[name] = append(positions[name], i)
+	}
+
+	for _, name := range names {
+		if err := a.insertRules(ctx, a.collections[name], lines, positions[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertRules inserts the lines at the given positions into col in one request.
+// Outside a transaction the rules get their keys up front, so retrying a request whose
+// response got lost leaves the rules it stored alone instead of storing them twice.
+func (a *Adapter) insertRules(ctx context.Context, col arangodb.Collection, lines []CasbinRule, positions []int) error {
+	docs := make([]interface{}, 0, len(positions))
+	for _, i := range positions {
+		rule := lines[i]
+		if rule.Key == "" && a.transaction == nil {
+			var err error
+			if rule.Key, err = newKey(); err != nil {
+				return err
+			}
+		}
+		doc, err := a.mapping.encode(rule)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+
+	var opts *arangodb.CollectionDocumentCreateOptions
+	var reader arangodb.CollectionDocumentCreateResponseReader
+	err := a.withRetry(ctx, func() error {
+		var err error
+		reader, err = col.CreateDocumentsWithOptions(ctx, docs, opts)
+
+		// Rules an earlier attempt stored are already there
+		ignore := arangodb.CollectionDocumentCreateOverwriteModeIgnore
+		opts = &arangodb.CollectionDocumentCreateOptions{OverwriteMode: &ignore}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, i := range positions {
+		meta, err := reader.Read()
+		if err != nil {
+			return err
+		}
+		lines[i].Key = meta.Key
+	}
+	return nil
+}
+
+// newKey returns a random document key.
+func newKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
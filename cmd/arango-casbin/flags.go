@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	arangoadapter "github.com/DenisBytes/arango-adapter"
+)
+
+// connectionFlags holds the connection and storage settings shared by every command. Each
+// one falls back to an environment variable, then to the adapter's defaults. The storage
+// settings have to match the ones of the application writing the rules.
+type connectionFlags struct {
+	endpoints  string
+	username   string
+	password   string
+	database   string
+	collection string
+	caCert     string
+	ptypeField string
+
+	audit             bool
+	auditCollection   string
+	versioning        bool
+	historyCollection string
+	softDelete        bool
+	actor             string
+
+	sectionCollections pairFlag // --section-collection sec=name
+	ptypeCollections   pairFlag // --ptype-collection ptype=name
+	fieldMappings      pairFlag // --field-mapping ptype=attribute,...
+	jsonFields         pairFlag // --json ptype=position,...
+	encryptedFields    pairFlag // --encrypt ptype=position,...
+}
+
+// register adds the connection flags to fs.
+func (c *connectionFlags) register(fs *flag.FlagSet) {
+	defaults := arangoadapter.NewConfig()
+	fs.StringVar(&c.endpoints, "endpoints", env("ARANGO_ENDPOINTS", strings.Join(defaults.Endpoints, ",")), "comma separated ArangoDB endpoints ($ARANGO_ENDPOINTS)")
+	fs.StringVar(&c.username, "username", env("ARANGO_USERNAME", defaults.Username), "database user ($ARANGO_USERNAME)")
+	fs.StringVar(&c.password, "password", "", "database password ($ARANGO_PASSWORD)")
+	fs.StringVar(&c.database, "database", env("ARANGO_DATABASE", defaults.DatabaseName), "database name ($ARANGO_DATABASE)")
+	fs.StringVar(&c.collection, "collection", env("ARANGO_COLLECTION", defaults.CollectionName), "policy collection name ($ARANGO_COLLECTION)")
+	fs.StringVar(&c.caCert, "ca-cert", env("ARANGO_CA_CERT", ""), "CA certificate file, enables TLS ($ARANGO_CA_CERT)")
+	fs.StringVar(&c.ptypeField, "ptype-field", env("ARANGO_PTYPE_FIELD", ""), "document attribute holding the ptype ($ARANGO_PTYPE_FIELD)")
+
+	fs.BoolVar(&c.audit, "audit", envBool("ARANGO_AUDIT"), "record changes in the audit log ($ARANGO_AUDIT)")
+	fs.StringVar(&c.auditCollection, "audit-collection", env("ARANGO_AUDIT_COLLECTION", ""), "audit collection name, enables --audit ($ARANGO_AUDIT_COLLECTION)")
+	fs.BoolVar(&c.versioning, "versioning", envBool("ARANGO_VERSIONING"), "keep the revision history up to date ($ARANGO_VERSIONING)")
+	fs.StringVar(&c.historyCollection, "history-collection", env("ARANGO_HISTORY_COLLECTION", ""), "history collection name, enables --versioning ($ARANGO_HISTORY_COLLECTION)")
+	fs.BoolVar(&c.softDelete, "soft-delete", envBool("ARANGO_SOFT_DELETE"), "flag removed rules instead of deleting them ($ARANGO_SOFT_DELETE)")
+	fs.StringVar(&c.actor, "actor", env("ARANGO_ACTOR", ""), "who changes are attributed to in the audit log ($ARANGO_ACTOR)")
+
+	c.sectionCollections.env = "ARANGO_SECTION_COLLECTIONS"
+	c.ptypeCollections.env = "ARANGO_PTYPE_COLLECTIONS"
+	c.fieldMappings.env = "ARANGO_FIELD_MAPPINGS"
+	c.jsonFields.env = "ARANGO_JSON_FIELDS"
+	c.encryptedFields.env = "ARANGO_ENCRYPTED_FIELDS"
+	fs.Var(&c.sectionCollections, "section-collection", "store section sec's rules in collection name, as sec=name, repeatable ($ARANGO_SECTION_COLLECTIONS, ; separated)")
+	fs.Var(&c.ptypeCollections, "ptype-collection", "store ptype's rules in collection name, as ptype=name, repeatable ($ARANGO_PTYPE_COLLECTIONS, ; separated)")
+	fs.Var(&c.fieldMappings, "field-mapping", "store ptype's values in these attributes, as ptype=attribute,..., repeatable ($ARANGO_FIELD_MAPPINGS, ; separated)")
+	fs.Var(&c.jsonFields, "json", "store these value positions (0 for v0) of ptype as JSON, as ptype=position,..., repeatable ($ARANGO_JSON_FIELDS, ; separated)")
+	fs.Var(&c.encryptedFields, "encrypt", "encrypt these value positions of ptype with the keys in $ARANGO_ENCRYPTION_KEYS, as ptype=position,..., repeatable ($ARANGO_ENCRYPTED_FIELDS, ; separated)")
+}
+
+// options converts the flags into adapter options.
+func (c *connectionFlags) options() ([]arangoadapter.Option, error) {
+	// The password isn't a flag default, so usage output doesn't show it
+	password := c.password
+	if password == "" {
+		password = env("ARANGO_PASSWORD", arangoadapter.NewConfig().Password)
+	}
+
+	opts := []arangoadapter.Option{
+		arangoadapter.WithEndpoints(strings.Split(c.endpoints, ",")...),
+		arangoadapter.WithAuthentication(c.username, password),
+		arangoadapter.WithDatabase(c.database),
+		arangoadapter.WithCollection(c.collection),
+	}
+	if c.caCert != "" {
+		opts = append(opts, arangoadapter.WithTLS(c.caCert))
+	}
+	if c.ptypeField != "" {
+		opts = append(opts, arangoadapter.WithPtypeField(c.ptypeField))
+	}
+	if c.audit || c.auditCollection != "" {
+		opts = append(opts, arangoadapter.WithAudit(c.auditCollection))
+	}
+	if c.versioning || c.historyCollection != "" {
+		opts = append(opts, arangoadapter.WithVersioning(c.historyCollection))
+	}
+	if c.softDelete {
+		opts = append(opts, arangoadapter.WithSoftDelete())
+	}
+
+	for _, flags := range []*pairFlag{&c.sectionCollections, &c.ptypeCollections, &c.fieldMappings, &c.jsonFields, &c.encryptedFields} {
+		if err := flags.fromEnv(); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range c.sectionCollections.pairs {
+		opts = append(opts, arangoadapter.WithSectionCollection(p.key, p.value))
+	}
+	for _, p := range c.ptypeCollections.pairs {
+		opts = append(opts, arangoadapter.WithPtypeCollection(p.key, p.value))
+	}
+	for _, p := range c.fieldMappings.pairs {
+		opts = append(opts, arangoadapter.WithFieldMapping(p.key, strings.Split(p.value, ",")...))
+	}
+
+	codecs, err := codecOptions(c.jsonFields.pairs, arangoadapter.JSONCodec{})
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, codecs...)
+	if len(c.encryptedFields.pairs) > 0 {
+		keys, err := encryptionKeys(os.Getenv("ARANGO_ENCRYPTION_KEYS"))
+		if err != nil {
+			return nil, err
+		}
+		codecs, err := codecOptions(c.encryptedFields.pairs, arangoadapter.NewEncryptionCodec(keys))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, codecs...)
+	}
+	return opts, nil
+}
+
+// withActor attributes the changes made with ctx to the --actor, if there is one.
+func (c *connectionFlags) withActor(ctx context.Context) context.Context {
+	if c.actor == "" {
+		return ctx
+	}
+	return arangoadapter.WithActor(ctx, c.actor)
+}
+
+// codecOptions converts ptype=position,... pairs into options using codec for those positions.
+func codecOptions(pairs []pair, codec arangoadapter.ValueCodec) ([]arangoadapter.Option, error) {
+	var opts []arangoadapter.Option
+	for _, p := range pairs {
+		for _, s := range strings.Split(p.value, ",") {
+			position, err := strconv.Atoi(s)
+			if err != nil || position < 0 || position > 5 {
+				return nil, fmt.Errorf("invalid value position %q for ptype %q, expected 0 to 5", s, p.key)
+			}
+			opts = append(opts, arangoadapter.WithValueCodec(p.key, position, codec))
+		}
+	}
+	return opts, nil
+}
+
+// encryptionKeys parses "id:base64 key,..." into static keys, encrypting with the first one.
+func encryptionKeys(s string) (arangoadapter.StaticKeys, error) {
+	keys := arangoadapter.StaticKeys{Keys: make(map[string][]byte)}
+	if s == "" {
+		return keys, errors.New("encrypted fields need keys in $ARANGO_ENCRYPTION_KEYS, as id:base64 key,...")
+	}
+	for _, entry := range strings.Split(s, ",") {
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return keys, errors.New("invalid $ARANGO_ENCRYPTION_KEYS, expected id:base64 key,...")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return keys, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		if keys.CurrentID == "" {
+			keys.CurrentID = id
+		}
+		keys.Keys[id] = key
+	}
+	return keys, nil
+}
+
+// env returns the environment variable name, or fallback if it isn't set.
+func env(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
+
+// envBool reports whether the environment variable name is set to a true value like "1" or
+// "true". Anything else, including an unset variable, is false.
+func envBool(name string) bool {
+	ok, _ := strconv.ParseBool(os.Getenv(name))
+	return ok
+}
+
+// filterFlag collects --filter field=value[,value...] flags into a Filter.
+type filterFlag struct {
+	filter arangoadapter.Filter
+}
+
+// String implements flag.Value.
+func (f *filterFlag) String() string {
+	return ""
+}
+
+// Set adds one field's values to the filter.
+func (f *filterFlag) Set(s string) error {
+	field, list, ok := strings.Cut(s, "=")
+	if !ok || list == "" {
+		return fmt.Errorf("expected field=value, got %q", s)
+	}
+	values := strings.Split(list, ",")
+
+	var target *[]string
+	switch strings.ToLower(field) {
+	case "ptype":
+		target = &f.filter.Ptype
+	case "v0":
+		target = &f.filter.V0
+	case "v1":
+		target = &f.filter.V1
+	case "v2":
+		target = &f.filter.V2
+	case "v3":
+		target = &f.filter.V3
+	case "v4":
+		target = &f.filter.V4
+	case "v5":
+		target = &f.filter.V5
+	default:
+		return fmt.Errorf("unknown filter field %q, expected ptype or v0 to v5", field)
+	}
+	*target = append(*target, values...)
+	return nil
+}
+
+// pair is one key=value setting.
+type pair struct {
+	key, value string
+}
+
+// pairFlag collects repeatable key=value flags. Without any flags, the settings are read
+// from the environment variable env, separated by semicolons.
+type pairFlag struct {
+	env   string
+	pairs []pair
+}
+
+// String implements flag.Value.
+func (f *pairFlag) String() string {
+	return ""
+}
+
+// Set adds one key=value setting.
+func (f *pairFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" || value == "" {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	f.pairs = append(f.pairs, pair{key, value})
+	return nil
+}
+
+// fromEnv reads the settings from the environment if no flags were given.
+func (f *pairFlag) fromEnv() error {
+	if len(f.pairs) > 0 || f.env == "" {
+		return nil
+	}
+	for _, s := range strings.Split(os.Getenv(f.env), ";") {
+		if s == "" {
+			continue
+		}
+		if err := f.Set(s); err != nil {
+			return fmt.Errorf("$%s: %w", f.env, err)
+		}
+	}
+	return nil
+}
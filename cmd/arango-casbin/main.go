@@ -0,0 +1,586 @@
+// Command arango-casbin manages Casbin policies stored in ArangoDB by the arango-adapter.
+//
+// Usage:
+//
+//	arango-casbin <command> [flags] [arguments]
+//
+// Commands:
+//
+//	list    [--filter field=values]...                               print matching rules with their document keys
+//	add     --section p|g | --model <model.conf> <ptype> <value>...  add a rule
+//	remove  --section p|g | --model <model.conf> <ptype> <value>...  remove a rule
+//	import  [--mode replace|merge|skip-existing] <file|->            import a policy.csv file
+//	export  [--filter field=values]... [file|-]                      export rules as a policy.csv file
+//	count   [--filter field=values]...                               count matching rules
+//	diff    --model <model.conf> <file>                              compare the stored policy with a policy.csv file
+//	check   --model <model.conf>                                     check the stored rules against a model
+//	repair  --model <model.conf> [--dry-run] [--orphaned-roles]      remove duplicates, unknown ptypes and optionally orphaned groupings
+//
+// Connection settings come from flags or the ARANGO_ENDPOINTS, ARANGO_USERNAME,
+// ARANGO_PASSWORD, ARANGO_DATABASE, ARANGO_COLLECTION and ARANGO_CA_CERT environment variables.
+// Collection routing, field mappings and value codecs have flags and variables of their own,
+// and have to match the application's adapter options. Commands that only read open the
+// collections as they are, without creating or migrating anything.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	arangoadapter "github.com/DenisBytes/arango-adapter"
+	"github.com/casbin/casbin/v2/model"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+)
+
+// errUsage is returned when a command's flags don't parse. The flag package has already
+// said why.
+var errUsage = errors.New("invalid flags")
+
+// errFound is returned by commands that found differences or problems, which they've
+// already reported, so the exit status says so.
+var errFound = errors.New("found differences or problems")
+
+// command is a subcommand of the tool.
+type command struct {
+	usage string
+	run   func(ctx context.Context, env *environment, args []string) error
+}
+
+var commands = map[string]command{
+	"list":   {"[--filter field=values]...", runList},
+	"add":    {"--section p|g | --model <model.conf> <ptype> <value>...", runAdd},
+	"remove": {"--section p|g | --model <model.conf> <ptype> <value>...", runRemove},
+	"import": {"[--mode replace|merge|skip-existing] <file|->", runImport},
+	"export": {"[--filter field=values]... [file|-]", runExport},
+	"count":  {"[--filter field=values]...", runCount},
+	"diff":   {"--model <model.conf> <file>", runDiff},
+	"check":  {"--model <model.conf>", runCheck},
+	"repair": {"--model <model.conf> [--dry-run] [--orphaned-roles]", runRepair},
+}
+
+// environment is what commands work with.
+type environment struct {
+	name    string
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	connect func(opts []arangoadapter.Option) (*arangoadapter.Adapter, error)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	env := &environment{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		connect: func(opts []arangoadapter.Option) (*arangoadapter.Adapter, error) {
+			return arangoadapter.NewAdapter(opts...)
+		},
+	}
+	os.Exit(run(ctx, env, os.Args[1:]))
+}
+
+// run runs the command named by args[0] and returns the exit status: 0 on success,
+// 1 on failure or when diff or check found something, 2 on usage errors.
+func run(ctx context.Context, env *environment, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printUsage(env.stderr)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(env.stderr, "unknown command %q\n\n", args[0])
+		printUsage(env.stderr)
+		return 2
+	}
+
+	env.name = args[0]
+	err := cmd.run(ctx, env, args[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.Is(err, errFound):
+		return 1
+	case errors.As(err, new(usageError)):
+		fmt.Fprintf(env.stderr, "%v\nusage: arango-casbin %s %s\n", err, env.name, cmd.usage)
+		return 2
+	default:
+		fmt.Fprintf(env.stderr, "arango-casbin %s: %v\n", env.name, err)
+		return 1
+	}
+}
+
+// printUsage lists the commands.
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: arango-casbin <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-7s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run arango-casbin <command> -h for the connection flags.")
+}
+
+// usageError is an error in how a command was called.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// flags returns a flag set for the command with the connection flags registered.
+func (env *environment) flags(conn *connectionFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet("arango-casbin "+env.name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	conn.register(fs)
+	return fs
+}
+
+// open connects to ArangoDB with the connection flags.
+func (env *environment) open(conn *connectionFlags) (*arangoadapter.Adapter, error) {
+	opts, err := conn.options()
+	if err != nil {
+		return nil, err
+	}
+	return env.connect(opts)
+}
+
+// openReadOnly is open for commands that only read, so they don't create the database or
+// collections, add indexes or migrate the stored rules.
+func (env *environment) openReadOnly(conn *connectionFlags) (*arangoadapter.Adapter, error) {
+	opts, err := conn.options()
+	if err != nil {
+		return nil, err
+	}
+	return env.connect(append(opts, arangoadapter.WithReadOnly()))
+}
+
+func runList(ctx context.Context, env *environment, args []string) error {
+	var conn connectionFlags
+	var filter filterFlag
+	fs := env.flags(&conn)
+	fs.Var(&filter, "filter", "only rules where field (ptype, v0 to v5) is one of the comma separated values, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	adapter, err := env.openReadOnly(&conn)
+	if err != nil {
+		return err
+	}
+	defer adapter.Close()
+
+	rules, err := adapter.GetRules(ctx, filter.filter)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		fmt.Fprintf(env.stdout, "%s\t%s\n", rule.Key, strings.Join(policyArray(rule), ", "))
+	}
+	return nil
+}
+
+func runAdd(ctx context.Context, env *environment, args []string) error {
+	var conn connectionFlags
+	var section sectionFlags
+	fs := env.flags(&conn)
+	section.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() < 2 || fs.Arg(0) == "" {
+		return usageError("expected a ptype and at least one value")
+	}
+	ptype := fs.Arg(0)
+	sec, err := section.resolve(ptype)
+	if err != nil {
+		return err
+	}
+
+	adapter, err := env.open(&conn)
+	if err != nil {
+		return err
+	}
+	defer adapter.Close()
+
+	ctx = conn.withActor(ctx)
+	return adapter.AddPolicyCtx(ctx, sec, ptype, fs.Args()[1:])
+}
+
+func runRemove(ctx context.Context, env *environment, args []string) error {
+	var conn connectionFlags
+	var section sectionFlags
+	fs := env.flags(&conn)
+	section.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() < 2 || fs.Arg(0) == "" {
+		return usageError("expected a ptype and at least one value")
+	}
+	ptype := fs.Arg(0)
+	sec, err := section.resolve(ptype)
+	if err != nil {
+		return err
+	}
+
+	adapter, err := env.open(&conn)
+	if err != nil {
+		return err
+	}
+	defer adapter.Close()
+
+	ctx = conn.withActor(ctx)
+	count, err := adapter.RemovePolicyCount(ctx, sec, ptype, fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "removed %d rules\n", count)
+	return nil
+}
+
+// sectionFlags say which section the rules of add and remove belong to, either directly
+// or through the model defining their ptype.
+type sectionFlags struct {
+	section string
+	model   string
+}
+
+// register adds the section flags to fs.
+func (s *sectionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.section, "section", "", "section of the ptype, p or g")
+	fs.StringVar(&s.model, "model", "", "Casbin model file defining the ptype, instead of --section")
+}
+
+// resolve returns the section of ptype.
+func (s *sectionFlags) resolve(ptype string) (string, error) {
+	switch {
+	case s.section == "p" || s.section == "g":
+		return s.section, nil
+	case s.section != "":
+		return "", usageError(fmt.Sprintf("unknown section %q, expected p or g", s.section))
+	case s.model == "":
+		return "", usageError("expected --section or --model")
+	}
+
+	m, err := model.NewModelFromFile(s.model)
+	if err != nil {
+		return "", err
+	}
+	for _, sec := range []string{"p", "g"} {
+		if _, ok := m[sec][ptype]; ok {
+			return sec, nil
+		}
+	}
+	return "", fmt.Errorf("ptype %q isn't defined in %s", ptype, s.model)
+}
+
+// importModes maps the --mode values of import to import modes.
+var importModes = map[string]arangoadapter.ImportMode{
+	"replace":       arangoadapter.ImportReplace,
+	"merge":         arangoadapter.ImportMerge,
+	"skip-existing": arangoadapter.ImportSkipExisting,
+}
+
+func runImport(ctx context.Context, env *environment, args []string) error {
+	var conn connectionFlags
+	fs := env.flags(&conn)
+	modeName := fs.String("mode", "merge", "what to do with stored rules: replace, merge or skip-existing")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	mode, ok := importModes[*modeName]
+	if !ok {
+		return usageError(fmt.Sprintf("unknown import mode %q", *modeName))
+	}
+	if fs.NArg() != 1 {
+		return usageError("expected a file, or - for standard input")
+	}
+
+	in := env.stdin
+	if fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	adapter, err := env.open(&conn)
+	if err != nil {
+		return err
+	}
+	defer adapter.Close()
+
+	imported, err := adapter.ImportCSV(conn.withActor(ctx), in, mode)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stderr, "imported %d rules\n", imported)
+	return nil
+}
+
+func runExport(ctx context.Context, env *environment, args []string) (err error) {
+	var conn connectionFlags
+	var filter filterFlag
+	fs := env.flags(&conn)
+	fs.Var(&filter, "filter", "only rules where field (ptype, v0 to v5) is one of the comma separated values, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 1 {
+		return usageError("expected at most one file")
+	}
+
+	adapter, err := env.openReadOnly(&conn)
+	if err != nil {
+		return err
+	}
+	defer adapter.Close()
+
+	out := env.stdout
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		f, err := os.Create(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		out = f
+	}
+
+	exported, err := adapter.ExportCSV(ctx, out, filter.filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stderr, "exported %d rules\n", exported)
+	return nil
+}
+
+func runCount(ctx context.Context, env *environment, args []string) error {
+	var conn connectionFlags
+	var filter filterFlag
+	fs := env.flags(&conn)
+	fs.Var(&filter, "filter", "only rules where field (ptype, v0 to v5) is one of the comma separated values, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	adapter, err := env.openReadOnly(&conn)
+	if err != nil {
+		return err
+	}
+	defer adapter.Close()
+
+	count, err := adapter.CountRules(ctx, filter.filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(env.stdout, count)
+	return nil
+}
+
+func runDiff(ctx context.Context, env *environment, args []string) error {
+	var conn connectionFlags
+	fs := env.flags(&conn)
+	modelPath := fs.String("model", "", "Casbin model file the policies are loaded with")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *modelPath == "" || fs.NArg() != 1 {
+		return usageError("expected --model and a policy file")
+	}
+
+	// Load both sides the way an enforcer would, so the comparison follows Casbin's rules
+	fileModel, err := model.NewModelFromFile(*modelPath)
+	if err != nil {
+		return err
+	}
+	if err := fileadapter.NewAdapter(fs.Arg(0)).LoadPolicy(fileModel); err != nil {
+		return err
+	}
+
+	adapter, err := env.openReadOnly(&conn)
+	if err != nil {
+		return err
+	}
+	defer adapter.Close()
+
+	storedModel, err := model.NewModelFromFile(*modelPath)
+	if err != nil {
+		return err
+	}
+	if err := adapter.LoadPolicyCtx(ctx, storedModel); err != nil {
+		return err
+	}
+
+	// "-" lines are only stored, "+" lines only in the file
+	removed, added := policyLines(storedModel), policyLines(fileModel)
+	differences := 0
+	for _, line := range sortedKeys(removed) {
+		if !added[line] {
+			fmt.Fprintln(env.stdout, "- "+line)
+			differences++
+		}
+	}
+	for _, line := range sortedKeys(added) {
+		if !removed[line] {
+			fmt.Fprintln(env.stdout, "+ "+line)
+			differences++
+		}
+	}
+	if differences > 0 {
+		return errFound
+	}
+	return nil
+}
+
+func runCheck(ctx context.Context, env *environment, args []string) error {
+	var conn connectionFlags
+	fs := env.flags(&conn)
+	modelPath := fs.String("model", "", "Casbin model file to check the rules against")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *modelPath == "" || fs.NArg() != 0 {
+		return usageError("expected --model")
+	}
+
+	m, err := model.NewModelFromFile(*modelPath)
+	if err != nil {
+		return err
+	}
+
+	adapter, err := env.openReadOnly(&conn)
+	if err != nil {
+		return err
+	}
+	defer adapter.Close()
+
+	rules, err := adapter.GetRules(ctx, arangoadapter.Filter{})
+	if err != nil {
+		return err
+	}
+
+	problems := checkRules(m, rules)
+	for _, problem := range problems {
+		fmt.Fprintln(env.stdout, problem)
+	}
+	if len(problems) > 0 {
+		return errFound
+	}
+	fmt.Fprintf(env.stderr, "checked %d rules\n", len(rules))
+	return nil
+}
+
+func runRepair(ctx context.Context, env *environment, args []string) error {
+	var conn connectionFlags
+	fs := env.flags(&conn)
+	modelPath := fs.String("model", "", "Casbin model file defining the ptypes to keep")
+	dryRun := fs.Bool("dry-run", false, "only print what would be removed")
+	orphanedRoles := fs.Bool("orphaned-roles", false, "also remove grouping rules of roles without policies")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *modelPath == "" || fs.NArg() != 0 {
+		return usageError("expected --model")
+	}
+
+	m, err := model.NewModelFromFile(*modelPath)
+	if err != nil {
+		return err
+	}
+
+	open := env.open
+	if *dryRun {
+		open = env.openReadOnly
+	}
+	adapter, err := open(&conn)
+	if err != nil {
+		return err
+	}
+	defer adapter.Close()
+
+	fixed, err := adapter.Repair(conn.withActor(ctx), m, arangoadapter.RepairOptions{DryRun: *dryRun, OrphanedRoles: *orphanedRoles})
+	if err != nil {
+		return err
+	}
+	removed := make(map[string]bool)
+	for _, d := range fixed {
+		fmt.Fprintf(env.stdout, "%s\t%s: %s\n", d.Rule.Key, strings.Join(policyArray(d.Rule), ", "), d.Message)
+		removed[d.Rule.Key] = true
+	}
+	if *dryRun {
+		fmt.Fprintf(env.stderr, "would remove %d rules\n", len(removed))
+	} else {
+		fmt.Fprintf(env.stderr, "removed %d rules\n", len(removed))
+	}
+	return nil
+}
+
+// checkRules reports rules the model doesn't define, rules with the wrong number of values,
+// empty values and duplicates, one line per problem.
+func checkRules(m model.Model, rules []arangoadapter.CasbinRule) []string {
+	var problems []string
+	for _, d := range arangoadapter.ValidateRules(m, rules) {
+		line := strings.Join(policyArray(d.Rule), ", ")
+		problems = append(problems, fmt.Sprintf("%s\t%s: %s", d.Rule.Key, line, d.Message))
+	}
+	return problems
+}
+
+// policyArray returns the rule's ptype and values with trailing empty values trimmed,
+// the way Casbin sees it.
+func policyArray(rule arangoadapter.CasbinRule) []string {
+	p := []string{rule.Ptype, rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5}
+	for len(p) > 1 && p[len(p)-1] == "" {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// policyLines returns every policy rule in m as a "ptype, values..." line.
+func policyLines(m model.Model) map[string]bool {
+	lines := make(map[string]bool)
+	for _, sec := range []string{"p", "g"} {
+		for ptype, assertion := range m[sec] {
+			for _, rule := range assertion.Policy {
+				lines[strings.Join(append([]string{ptype}, rule...), ", ")] = true
+			}
+		}
+	}
+	return lines
+}
+
+// sortedKeys returns the keys of set in order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
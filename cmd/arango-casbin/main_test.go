@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	arangoadapter "github.com/DenisBytes/arango-adapter"
+	"github.com/casbin/casbin/v2/model"
+)
+
+func newTestEnvironment() (*environment, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	return &environment{
+		stdin:  strings.NewReader(""),
+		stdout: &stdout,
+		stderr: &stderr,
+		connect: func([]arangoadapter.Option) (*arangoadapter.Adapter, error) {
+			return nil, errors.New("connection refused")
+		},
+	}, &stdout, &stderr
+}
+
+func TestFilterFlag(t *testing.T) {
+	var f filterFlag
+	for _, s := range []string{"ptype=p,g", "v0=alice", "V0=bob"} {
+		if err := f.Set(s); err != nil {
+			t.Fatalf("Failed to set %q: %v", s, err)
+		}
+	}
+	expected := arangoadapter.Filter{Ptype: []string{"p", "g"}, V0: []string{"alice", "bob"}}
+	if !reflect.DeepEqual(f.filter, expected) {
+		t.Errorf("Unexpected filter: %+v", f.filter)
+	}
+
+	for _, s := range []string{"v6=alice", "alice", "v0="} {
+		if err := f.Set(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
+
+func TestConnectionFlagsFromEnvironment(t *testing.T) {
+	t.Setenv("ARANGO_DATABASE", "authz")
+	t.Setenv("ARANGO_ENDPOINTS", "http://a:8529,http://b:8529")
+
+	env, _, _ := newTestEnvironment()
+	env.name = "list"
+	var conn connectionFlags
+	fs := env.flags(&conn)
+	if err := fs.Parse([]string{"--collection", "rules"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	opts, err := conn.options()
+	if err != nil {
+		t.Fatalf("Failed to convert flags: %v", err)
+	}
+	cfg := arangoadapter.NewConfig(opts...)
+	if cfg.DatabaseName != "authz" || cfg.CollectionName != "rules" || len(cfg.Endpoints) != 2 {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	if cfg.Username != "root" {
+		t.Errorf("Expected the adapter's default user, got %q", cfg.Username)
+	}
+}
+
+func TestStorageFlags(t *testing.T) {
+	t.Setenv("ARANGO_FIELD_MAPPINGS", "p=subject,resource,action;g=member,role")
+	t.Setenv("ARANGO_ENCRYPTION_KEYS", "k1:"+base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	env, _, _ := newTestEnvironment()
+	env.name = "list"
+	var conn connectionFlags
+	fs := env.flags(&conn)
+	err := fs.Parse([]string{"--ptype-collection", "g=roles", "--json", "p=2", "--encrypt", "p=0,1", "--ptype-field", "type"})
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	opts, err := conn.options()
+	if err != nil {
+		t.Fatalf("Failed to convert flags: %v", err)
+	}
+	cfg := arangoadapter.NewConfig(opts...)
+	if cfg.PtypeCollections["g"] != "roles" || cfg.PtypeField != "type" {
+		t.Errorf("Unexpected routing: %+v, %q", cfg.PtypeCollections, cfg.PtypeField)
+	}
+	if !reflect.DeepEqual(cfg.FieldMappings["g"], []string{"member", "role"}) || len(cfg.FieldMappings["p"]) != 3 {
+		t.Errorf("Unexpected field mappings: %+v", cfg.FieldMappings)
+	}
+	if _, ok := cfg.ValueCodecs["p"][2].(arangoadapter.JSONCodec); !ok {
+		t.Errorf("Expected a JSON codec for p position 2, got %v", cfg.ValueCodecs["p"][2])
+	}
+	for _, i := range []int{0, 1} {
+		if _, ok := cfg.ValueCodecs["p"][i].(*arangoadapter.EncryptionCodec); !ok {
+			t.Errorf("Expected an encryption codec for p position %d, got %v", i, cfg.ValueCodecs["p"][i])
+		}
+	}
+
+	conn.jsonFields.pairs = []pair{{"p", "6"}}
+	if _, err := conn.options(); err == nil {
+		t.Error("Expected an error for position 6")
+	}
+}
+
+func TestChangeFlags(t *testing.T) {
+	t.Setenv("ARANGO_SOFT_DELETE", "true")
+	t.Setenv("ARANGO_ACTOR", "deploy-bot")
+
+	env, _, _ := newTestEnvironment()
+	env.name = "add"
+	var conn connectionFlags
+	fs := env.flags(&conn)
+	if err := fs.Parse([]string{"--audit", "--history-collection", "rule_history"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	opts, err := conn.options()
+	if err != nil {
+		t.Fatalf("Failed to convert flags: %v", err)
+	}
+	cfg := arangoadapter.NewConfig(opts...)
+	if !cfg.AuditEnabled || !cfg.VersioningEnabled || cfg.HistoryCollectionName != "rule_history" || !cfg.SoftDelete {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	if actor := arangoadapter.ActorFromContext(conn.withActor(context.Background())); actor != "deploy-bot" {
+		t.Errorf("Expected changes attributed to deploy-bot, got %q", actor)
+	}
+}
+
+func TestSectionFlags(t *testing.T) {
+	modelFile := filepath.Join(t.TempDir(), "model.conf")
+	conf := "[request_definition]\nr = sub, obj, act\n\n[policy_definition]\np = sub, obj, act\n\n" +
+		"[role_definition]\ng = _, _\ng2 = _, _\n\n[policy_effect]\ne = some(where (p.eft == allow))\n\n" +
+		"[matchers]\nm = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act\n"
+	if err := os.WriteFile(modelFile, []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := sectionFlags{model: modelFile}
+	if sec, err := s.resolve("g2"); err != nil || sec != "g" {
+		t.Errorf("Expected g2 in section g, got %q (%v)", sec, err)
+	}
+	if _, err := s.resolve("p3"); err == nil {
+		t.Error("Expected an error for a ptype missing from the model")
+	}
+	if sec, _ := (&sectionFlags{section: "g"}).resolve("roles"); sec != "g" {
+		t.Errorf("Expected the given section, got %q", sec)
+	}
+}
+
+func TestEncryptionKeys(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, 16))
+	keys, err := encryptionKeys("new:" + key + ",old:" + key)
+	if err != nil {
+		t.Fatalf("Failed to parse keys: %v", err)
+	}
+	if keys.CurrentID != "new" || len(keys.Keys) != 2 || len(keys.Keys["old"]) != 16 {
+		t.Errorf("Unexpected keys: %+v", keys)
+	}
+
+	for _, s := range []string{"", "nokey", ":" + key, "k1:not base64"} {
+		if _, err := encryptionKeys(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
+
+func TestRunExitStatus(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		args   []string
+		status int
+	}{
+		{nil, 2},
+		{[]string{"frobnicate"}, 2},
+		{[]string{"add", "p"}, 2},
+		{[]string{"add", "p", "alice", "data1", "read"}, 2},
+		{[]string{"remove", "--section", "x", "p", "alice"}, 2},
+		{[]string{"import", "--mode", "overwrite", "-"}, 2},
+		{[]string{"check"}, 2},
+		{[]string{"repair", "--dry-run"}, 2},
+		{[]string{"list", "--filter", "v9=x"}, 2},
+		{[]string{"list"}, 1},
+	}
+	for _, test := range tests {
+		env, _, stderr := newTestEnvironment()
+		if status := run(ctx, env, test.args); status != test.status {
+			t.Errorf("%v: expected status %d, got %d (%s)", test.args, test.status, status, stderr.String())
+		}
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	m, err := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	problems := checkRules(m, []arangoadapter.CasbinRule{
+		{Key: "1", Ptype: "p", V0: "alice", V1: "data1", V2: "read"},
+		{Key: "2", Ptype: "p", V0: "alice", V1: "data1", V2: "read"},
+		{Key: "3", Ptype: "p", V0: "bob", V1: "data1"},
+		{Key: "4", Ptype: "p2", V0: "bob", V1: "data1", V2: "read"},
+		{Key: "5", Ptype: "g", V0: "alice", V1: "admin"},
+	})
+	if len(problems) != 3 {
+		t.Fatalf("Expected 3 problems, got %q", problems)
+	}
+	for i, expected := range []string{"duplicate of 1", "has 2 values", `"p2" isn't defined`} {
+		if !strings.Contains(problems[i], expected) {
+			t.Errorf("Expected %q in %q", expected, problems[i])
+		}
+	}
+}
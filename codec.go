@@ -0,0 +1,184 @@
+package arangoadapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ValueCodec converts rule values between the strings Casbin works with and what's stored
+// in ArangoDB. Register one per ptype and position with WithValueCodec.
+type ValueCodec interface {
+	// Encode converts a Casbin value into the value to store.
+	Encode(value string) (interface{}, error)
+
+	// Decode converts a stored value, as decoded from JSON with numbers kept as
+	// json.Number, back into a Casbin value.
+	Decode(stored interface{}) (string, error)
+}
+
+// JSONCodec stores values that are valid JSON, such as numbers, arrays and objects,
+// natively, so AQL can compare them as such. Anything else is stored as a plain string.
+// Values come back in compact form with object keys sorted, e.g. "[1, 2]" loads as "[1,2]".
+type JSONCodec struct{}
+
+// Encode parses value as JSON, falling back to the string itself.
+func (JSONCodec) Encode(value string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+
+	var parsed interface{}
+	if err := decoder.Decode(&parsed); err != nil || decoder.More() {
+		return value, nil
+	}
+	if _, ok := parsed.(string); ok {
+		// Quoted strings stay quoted, so they come back the same way
+		return value, nil
+	}
+	return parsed, nil
+}
+
+// Decode returns strings as they are and everything else as JSON.
+func (JSONCodec) Decode(stored interface{}) (string, error) {
+	if s, ok := stored.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(stored)
+	return string(data), err
+}
+
+// codec returns the codec for value i of a ptype's rules, or nil.
+func (m *mapping) codec(ptype string, i int) ValueCodec {
+	return m.codecs[ptype][i]
+}
+
+// encodeValue converts value i of a ptype's rule into what's stored.
+func (m *mapping) encodeValue(ptype string, i int, value string) (interface{}, error) {
+	codec := m.codec(ptype, i)
+	if codec == nil {
+		return value, nil
+	}
+	stored, err := codec.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("encoding value %d of %q: %w", i, ptype, err)
+	}
+	return stored, nil
+}
+
+// decodeValue converts the stored value i of a ptype's rule back into a string.
+// Without a codec, anything but a string comes back as its JSON text.
+func (m *mapping) decodeValue(ptype string, i int, raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	codec := m.codec(ptype, i)
+	if codec == nil {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s, nil
+		}
+		return string(raw), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var stored interface{}
+	if err := decoder.Decode(&stored); err != nil {
+		return "", err
+	}
+	return codec.Decode(stored)
+}
+
+// encodeAny converts filter values for position i into every form they could be stored in,
+// since the rules a filter matches may have different codecs.
+func (m *mapping) encodeAny(i int, values []string) ([]interface{}, error) {
+	stored := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		stored = append(stored, value)
+	}
+
+	ptypes := make([]string, 0, len(m.codecs))
+	for ptype := range m.codecs {
+		ptypes = append(ptypes, ptype)
+	}
+	sort.Strings(ptypes)
+
+	for _, ptype := range ptypes {
+		if m.codec(ptype, i) == nil {
+			continue
+		}
+		for _, value := range values {
+			encoded, err := m.encodeValue(ptype, i, value)
+			if err != nil {
+				return nil, err
+			}
+			if !containsValue(stored, encoded) {
+				stored = append(stored, encoded)
+			}
+		}
+	}
+	return stored, nil
+}
+
+// containsValue reports whether values already holds value.
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameCodec reports whether a and b are the same codec, so the values they store can be
+// compared as stored. Codecs that can't be compared are never the same.
+func sameCodec(a, b ValueCodec) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.Type() == vb.Type() && va.Comparable() && va.Equal(vb)
+}
+
+// Condition compares a rule value with an AQL operator, for values stored natively
+// through a codec. Value is used as is, so compare numeric fields with numbers.
+//
+// Supported operators are ==, !=, <, <=, >, >=, IN (the field is one of Value's elements)
+// and CONTAINS (the field is an array holding Value).
+type Condition struct {
+	Field int // Rule position, 0 for v0
+	Op    string
+	Value interface{}
+}
+
+// conditionOperators maps supported operators to AQL, with %[1]s the field and %[2]s the value.
+var conditionOperators = map[string]string{
+	"==":       "%[1]s == %[2]s",
+	"!=":       "%[1]s != %[2]s",
+	"<":        "%[1]s < %[2]s",
+	"<=":       "%[1]s <= %[2]s",
+	">":        "%[1]s > %[2]s",
+	">=":       "%[1]s >= %[2]s",
+	"IN":       "%[1]s IN %[2]s",
+	"CONTAINS": "%[2]s IN %[1]s",
+}
+
+// condition builds the AQL for the n-th condition of a filter and adds its bind variable.
+func (m *mapping) condition(n int, c Condition, bindVars map[string]interface{}) (string, error) {
+	if c.Field < 0 || c.Field >= len(fieldNames) {
+		return "", fmt.Errorf("condition %d: invalid field %d", n, c.Field)
+	}
+	format, ok := conditionOperators[strings.ToUpper(c.Op)]
+	if !ok {
+		return "", fmt.Errorf("condition %d: unsupported operator %q", n, c.Op)
+	}
+
+	// Named after the field, so query logging redacts it like the field itself
+	name := fmt.Sprintf("cond%d_%s", n, fieldNames[c.Field])
+	bindVars[name] = c.Value
+	return fmt.Sprintf(format, m.anyFieldExpr(c.Field), "@"+name), nil
+}
@@ -0,0 +1,173 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONCodec(t *testing.T) {
+	codec := JSONCodec{}
+	tests := map[string]interface{}{
+		"42":       json.Number("42"),
+		"[1,2]":    []interface{}{json.Number("1"), json.Number("2")},
+		`{"a":1}`:  map[string]interface{}{"a": json.Number("1")},
+		"alice":    "alice",
+		`"quoted"`: `"quoted"`,
+		"1 2":      "1 2",
+		"":         "",
+		"true":     true,
+	}
+	for value, expected := range tests {
+		stored, err := codec.Encode(value)
+		if err != nil {
+			t.Fatalf("Failed to encode %q: %v", value, err)
+		}
+		got, _ := json.Marshal(stored)
+		want, _ := json.Marshal(expected)
+		if string(got) != string(want) {
+			t.Errorf("Encode(%q) = %s, want %s", value, got, want)
+		}
+
+		decoded, err := codec.Decode(stored)
+		if err != nil {
+			t.Fatalf("Failed to decode %q: %v", value, err)
+		}
+		if decoded != value {
+			t.Errorf("Round trip of %q gave %q", value, decoded)
+		}
+	}
+
+	if decoded, _ := codec.Decode([]interface{}{json.Number("1"), json.Number("2")}); decoded != "[1,2]" {
+		t.Errorf("Arrays should decode compactly, got %q", decoded)
+	}
+}
+
+func TestMappingWithCodec(t *testing.T) {
+	m := newTestMapping(t, WithValueCodec("p", 3, JSONCodec{}))
+
+	if _, err := newMapping(NewConfig(WithValueCodec("p", 6, JSONCodec{}))); err == nil {
+		t.Error("Codec outside the rule's positions should be rejected")
+	}
+
+	rule := CasbinRule{Ptype: "p", V0: "alice", V1: "data1", V2: "read", V3: "5"}
+	doc, err := m.encode(rule)
+	if err != nil {
+		t.Fatalf("Failed to encode rule: %v", err)
+	}
+	data, _ := json.Marshal(doc)
+	var stored map[string]interface{}
+	_ = json.Unmarshal(data, &stored)
+	if stored["v3"] != float64(5) || stored["v0"] != "alice" {
+		t.Errorf("Expected v3 stored as a number, got %s", data)
+	}
+
+	decoded, err := m.decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode rule: %v", err)
+	}
+	if decoded.identity() != rule.identity() {
+		t.Errorf("Round trip gave %+v", decoded)
+	}
+
+	// Values of other ptypes that aren't strings come back as JSON
+	decoded, err = m.decode([]byte(`{"ptype":"g","v0":"alice","v1":7}`))
+	if err != nil || decoded.V1 != "7" {
+		t.Errorf("Expected v1 loaded as \"7\", got %+v (%v)", decoded, err)
+	}
+
+	_, bindVars, err := m.ruleConditions(rule)
+	if err != nil {
+		t.Fatalf("Failed to build conditions: %v", err)
+	}
+	if bindVars["v3"] != json.Number("5") {
+		t.Errorf("Expected v3 matched as a number, got %#v", bindVars["v3"])
+	}
+
+	_, bindVars, err = m.filterConditions(Filter{V3: []string{"5"}})
+	if err != nil {
+		t.Fatalf("Failed to build filter: %v", err)
+	}
+	if values := bindVars["v3"].([]interface{}); len(values) != 2 || values[0] != "5" || values[1] != json.Number("5") {
+		t.Errorf("Filter should match both the string and the number, got %#v", values)
+	}
+}
+
+func TestFilterConditionOperators(t *testing.T) {
+	m := newTestMapping(t)
+
+	conditions, bindVars, err := m.filterConditions(Filter{Conditions: []Condition{
+		{Field: 3, Op: ">=", Value: 3},
+		{Field: 4, Op: "contains", Value: "eu"},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to build filter: %v", err)
+	}
+	if conditions != notDeleted+" AND doc.v3 >= @cond0_v3 AND @cond1_v4 IN doc.v4" {
+		t.Errorf("Unexpected conditions: %s", conditions)
+	}
+	if bindVars["cond0_v3"] != 3 || bindVars["cond1_v4"] != "eu" {
+		t.Errorf("Unexpected bind variables: %v", bindVars)
+	}
+
+	if _, _, err := m.filterConditions(Filter{Conditions: []Condition{{Field: 0, Op: "LIKE", Value: "a%"}}}); err == nil {
+		t.Error("Unsupported operator should be rejected")
+	}
+	if _, _, err := m.filterConditions(Filter{Conditions: []Condition{{Field: 6, Op: "==", Value: 1}}}); err == nil {
+		t.Error("Invalid field should be rejected")
+	}
+}
+
+func TestValueCodecIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t, WithValueCodec("p", 3, JSONCodec{}))
+	defer teardownTestAdapter(t, adapter)
+
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read", "5"})
+	_ = adapter.AddPolicy("p", "p", []string{"bob", "data2", "read", "2"})
+	_ = adapter.AddPolicy("p", "p", []string{"carol", "data3", "read", "[1,2]"})
+
+	m := newTestModel()
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "data1", "read", "5"}); !ok {
+		t.Error("Numeric value should load back as a string")
+	}
+
+	rules, err := adapter.GetRules(context.Background(), Filter{Conditions: []Condition{{Field: 3, Op: ">", Value: 3}}})
+	if err != nil {
+		t.Fatalf("Failed to get rules: %v", err)
+	}
+	if len(rules) != 1 || rules[0].V0 != "alice" {
+		t.Errorf("Expected only alice's rule, got %v", rules)
+	}
+
+	rules, _ = adapter.GetRules(context.Background(), Filter{Conditions: []Condition{{Field: 3, Op: "CONTAINS", Value: 2}}})
+	if len(rules) != 1 || rules[0].V0 != "carol" {
+		t.Errorf("Expected only carol's rule, got %v", rules)
+	}
+
+	if err := adapter.RemovePolicy("p", "p", []string{"bob", "data2", "read", "2"}); err != nil {
+		t.Fatalf("Failed to remove numeric rule: %v", err)
+	}
+}
+
+func TestSameCodec(t *testing.T) {
+	encryption := newTestEncryptionCodec()
+	tests := []struct {
+		a, b ValueCodec
+		want bool
+	}{
+		{nil, nil, true},
+		{JSONCodec{}, JSONCodec{}, true},
+		{encryption, encryption, true},
+		{nil, JSONCodec{}, false},
+		{JSONCodec{}, encryption, false},
+		{encryption, newTestEncryptionCodec(), false},
+	}
+	for _, tt := range tests {
+		if got := sameCodec(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameCodec(%T, %T) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// RepairOptions controls Repair.
+type RepairOptions struct {
+	DryRun        bool      // Only report what would be removed
+	Problems      []Problem // Problems to fix (default: everything Check finds but orphaned roles)
+	OrphanedRoles bool      // Also remove grouping rules of orphaned roles, which may be assigned ahead of their policies
+}
+
+// Check looks for rules that have built up in the collection over time and can go:
+// duplicates, grouping rules for roles nothing refers to anymore, and rules whose ptype
+// m doesn't define. The work is done in AQL, without loading the policy. Duplicates are
+// reported for every copy but the oldest.
+//
+// A role counts as referenced if any value of a policy rule matches it, so roles used as
+// objects, like with g2, aren't reported. Roles that inherit from other roles aren't
+// reported either; once a chain is broken, each Repair removes one more link of it. Roles
+// are only checked if they're stored with the same codec as the values they're compared
+// with, e.g. not when only some positions are encrypted; a warning is logged instead.
+func (a *Adapter) Check(ctx context.Context, m model.Model) (diagnostics []Diagnostic, err error) {
+	ctx, op := a.startOperation(ctx, "Check")
+	defer func() {
+		op.setRuleCount(len(diagnostics))
+		op.end(err)
+	}()
+
+	return a.check(ctx, m)
+}
+
+// Repair removes the rules with the problems Check finds, or the ones in opts.Problems,
+// in one transaction. Orphaned roles are only removed when opts asks for them. It returns the diagnostics for the rules removed, or for the rules
+// that would be in a dry run. Removals are audited and soft deleted like any others.
+func (a *Adapter) Repair(ctx context.Context, m model.Model, opts RepairOptions) (fixed []Diagnostic, err error) {
+	ctx, op := a.startWrite(ctx, "Repair")
+	defer func() {
+		op.setRuleCount(len(fixed))
+		op.end(err)
+	}()
+
+	wanted := func(problem Problem) bool {
+		if problem == ProblemOrphanedRole && opts.OrphanedRoles {
+			return true
+		}
+		if len(opts.Problems) == 0 {
+			return problem != ProblemOrphanedRole
+		}
+		for _, p := range opts.Problems {
+			if p == problem {
+				return true
+			}
+		}
+		return false
+	}
+
+	repair := func(tx *Adapter) error {
+		diagnostics, err := tx.check(ctx, m)
+		if err != nil {
+			return err
+		}
+
+		fixed = nil
+		var keys []string
+		seen := make(map[string]bool)
+		for _, d := range diagnostics {
+			if !wanted(d.Problem) {
+				continue
+			}
+			fixed = append(fixed, d)
+			if !seen[d.Rule.Key] {
+				seen[d.Rule.Key] = true
+				keys = append(keys, d.Rule.Key)
+			}
+		}
+		if opts.DryRun || len(keys) == 0 {
+			return nil
+		}
+
+		_, _, err = tx.removeRules(ctx, AuditRepairPolicy, tx.routing.names(), "doc._key IN @keys", map[string]interface{}{"keys": keys}, false)
+		return err
+	}
+
+	if opts.DryRun {
+		err = repair(a)
+	} else {
+		err = a.inTransaction(ctx, repair)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fixed, nil
+}
+
+// check does the work for Check.
+func (a *Adapter) check(ctx context.Context, m model.Model) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+
+	var policyPtypes, groupingPtypes []string
+	for ptype := range m["p"] {
+		policyPtypes = append(policyPtypes, ptype)
+	}
+	for ptype := range m["g"] {
+		groupingPtypes = append(groupingPtypes, ptype)
+	}
+	sort.Strings(policyPtypes)
+	sort.Strings(groupingPtypes)
+
+	// Rules the model has no place for, including ones without a ptype
+	ptypes := append(append([]string{}, policyPtypes...), groupingPtypes...)
+	conditions := notDeleted + " && " + a.mapping.ptypeExpr() + " NOT IN @ptypes"
+	err := a.eachRule(ctx, a.routing.names(), conditions, map[string]interface{}{"ptypes": ptypes}, func(rule CasbinRule) error {
+		diagnostics = append(diagnostics, Diagnostic{
+			Rule:    rule,
+			Problem: ProblemUnknownPtype,
+			Message: fmt.Sprintf("ptype %q isn't defined in the model", rule.Ptype),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates, err := a.duplicateRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics = append(diagnostics, duplicates...)
+
+	if len(policyPtypes) > 0 {
+		for _, ptype := range groupingPtypes {
+			orphaned, err := a.orphanedGroupings(ctx, ptype, policyPtypes)
+			if err != nil {
+				return nil, err
+			}
+			diagnostics = append(diagnostics, orphaned...)
+		}
+	}
+	return diagnostics, nil
+}
+
+// duplicateRules returns a diagnostic for every rule with the same ptype and values as an
+// older one in the same collection.
+func (a *Adapter) duplicateRules(ctx context.Context) ([]Diagnostic, error) {
+	identity := []string{a.mapping.ptypeExpr()}
+	for i := range fieldNames {
+		identity = append(identity, "NOT_NULL("+a.mapping.anyFieldExpr(i)+", \"\")")
+	}
+	query := "FOR doc IN @@collection FILTER " + notDeleted +
+		" COLLECT identity = [" + strings.Join(identity, ", ") + "] INTO docs = doc" +
+		" FILTER LENGTH(docs) > 1" +
+		" LET sorted = (FOR d IN docs SORT d.createdAt, d._key RETURN d)" +
+		" RETURN { kept: sorted[0]._key, extra: SLICE(sorted, 1) }"
+
+	var diagnostics []Diagnostic
+	for _, name := range a.routing.names() {
+		err := a.readDocuments(ctx, query, map[string]interface{}{"@collection": name}, func(raw json.RawMessage) error {
+			var group struct {
+				Kept  string            `json:"kept"`
+				Extra []json.RawMessage `json:"extra"`
+			}
+			if err := json.Unmarshal(raw, &group); err != nil {
+				return err
+			}
+			for _, doc := range group.Extra {
+				rule, err := a.mapping.decode(doc)
+				if err != nil {
+					return err
+				}
+				diagnostics = append(diagnostics, Diagnostic{
+					Rule:    rule,
+					Problem: ProblemDuplicate,
+					Message: "duplicate of " + group.Kept,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return diagnostics, nil
+}
+
+// orphanedGroupings returns a diagnostic for every grouping rule of ptype whose role isn't
+// referenced by a rule of policyPtypes and isn't a member of another role. Values are
+// matched as stored and compared decoded, so the check is skipped if the values it compares
+// don't all go through the same codec.
+func (a *Adapter) orphanedGroupings(ctx context.Context, ptype string, policyPtypes []string) ([]Diagnostic, error) {
+	names := a.routing.namesFor([]string{ptype})
+	member, role := a.mapping.fieldExpr(ptype, 0), a.mapping.fieldExpr(ptype, 1)
+	if member == "" || role == "" {
+		return nil, nil
+	}
+
+	codec := a.mapping.codec(ptype, 1)
+	same := sameCodec(a.mapping.codec(ptype, 0), codec)
+	for _, p := range policyPtypes {
+		for i := range a.mapping.fieldsFor(p) {
+			same = same && sameCodec(a.mapping.codec(p, i), codec)
+		}
+	}
+	if !same {
+		a.logger.WarnContext(ctx, "skipped orphaned role check, role values are stored with different codecs",
+			slog.String("ptype", ptype),
+		)
+		return nil, nil
+	}
+	byPtype := notDeleted + " && " + a.mapping.ptypeExpr() + " == @ptype"
+
+	stored, err := a.distinctValues(ctx, names,
+		"FOR doc IN @@collection FILTER "+byPtype+" && "+role+" NOT IN [null, \"\"] COLLECT value = "+role+" RETURN value",
+		map[string]interface{}{"ptype": ptype})
+	if err != nil || len(stored) == 0 {
+		return nil, err
+	}
+	roles := make(map[string][]json.RawMessage) // Stored forms by decoded role
+	for _, value := range stored {
+		decoded, err := a.mapping.decodeValue(ptype, 1, value)
+		if err != nil {
+			return nil, err
+		}
+		roles[decoded] = append(roles[decoded], value)
+	}
+
+	// Every compared position uses the role's codec, so it decodes all of them
+	used := make(map[string]bool)
+	markUsed := func(values []json.RawMessage) error {
+		for _, value := range values {
+			decoded, err := a.mapping.decodeValue(ptype, 1, value)
+			if err != nil {
+				return err
+			}
+			used[decoded] = true
+		}
+		return nil
+	}
+
+	for _, p := range policyPtypes {
+		fields := a.mapping.fieldsFor(p)
+		values := make([]string, len(fields))
+		for i := range fields {
+			values[i] = a.mapping.fieldExpr(p, i)
+		}
+		referenced, err := a.distinctValues(ctx, a.routing.namesFor([]string{p}),
+			"FOR doc IN @@collection FILTER "+notDeleted+" && "+a.mapping.ptypeExpr()+" == @ptype"+
+				" FOR value IN ["+strings.Join(values, ", ")+"] FILTER value IN @roles COLLECT v = value RETURN v",
+			map[string]interface{}{"ptype": p, "roles": stored})
+		if err != nil {
+			return nil, err
+		}
+		if err := markUsed(referenced); err != nil {
+			return nil, err
+		}
+	}
+	members, err := a.distinctValues(ctx, names,
+		"FOR doc IN @@collection FILTER "+byPtype+" && "+member+" IN @roles COLLECT value = "+member+" RETURN value",
+		map[string]interface{}{"ptype": ptype, "roles": stored})
+	if err != nil {
+		return nil, err
+	}
+	if err := markUsed(members); err != nil {
+		return nil, err
+	}
+
+	var orphans []json.RawMessage
+	for decoded, forms := range roles {
+		if !used[decoded] {
+			orphans = append(orphans, forms...)
+		}
+	}
+	if len(orphans) == 0 {
+		return nil, nil
+	}
+
+	var diagnostics []Diagnostic
+	bindVars := map[string]interface{}{"ptype": ptype, "orphans": orphans}
+	err = a.eachRule(ctx, names, byPtype+" && "+role+" IN @orphans", bindVars, func(rule CasbinRule) error {
+		diagnostics = append(diagnostics, Diagnostic{
+			Rule:    rule,
+			Problem: ProblemOrphanedRole,
+			Message: fmt.Sprintf("role %q has no policies", rule.V1),
+		})
+		return nil
+	})
+	return diagnostics, err
+}
+
+// distinctValues runs query in each of the named collections and returns the distinct
+// values it returns, as stored.
+func (a *Adapter) distinctValues(ctx context.Context, names []string, query string, bindVars map[string]interface{}) ([]json.RawMessage, error) {
+	var values []json.RawMessage
+	seen := make(map[string]bool)
+	for _, name := range names {
+		bindVars["@collection"] = name
+		err := a.readDocuments(ctx, query, bindVars, func(raw json.RawMessage) error {
+			if !seen[string(raw)] {
+				seen[string(raw)] = true
+				values = append(values, raw)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,138 @@
+package arangoadapter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckAndRepair(t *testing.T) {
+	adapter := setupTestAdapter(t, WithAudit(""))
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = adapter.AddPolicy("p", "p", []string{"admin", "data2", "write"})
+	_ = adapter.AddPolicy("g", "g", []string{"bob", "admin"})
+	_ = adapter.AddPolicy("g", "g", []string{"editor", "admin"})
+	_ = adapter.AddPolicy("g", "g", []string{"dave", "editor"}) // Inherits through editor
+	_ = adapter.AddPolicy("g", "g", []string{"carol", "ghost"}) // No policies for ghost
+	for _, doc := range []CasbinRule{
+		{Ptype: "p", V0: "alice", V1: "data1", V2: "read"},
+		{Ptype: "p9", V0: "alice", V1: "data1"},
+	} {
+		if _, err := adapter.collection.CreateDocument(ctx, doc); err != nil {
+			t.Fatalf("Failed to insert rule: %v", err)
+		}
+	}
+
+	diagnostics, err := adapter.Check(ctx, newTestModel())
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	problems := make(map[Problem]CasbinRule)
+	for _, d := range diagnostics {
+		problems[d.Problem] = d.Rule
+	}
+	if len(diagnostics) != 3 || problems[ProblemUnknownPtype].Ptype != "p9" ||
+		problems[ProblemDuplicate].V0 != "alice" || problems[ProblemOrphanedRole].V1 != "ghost" {
+		t.Fatalf("Unexpected diagnostics: %+v", diagnostics)
+	}
+
+	fixed, err := adapter.Repair(ctx, newTestModel(), RepairOptions{DryRun: true})
+	if err != nil || len(fixed) != 2 {
+		t.Fatalf("Expected a dry run to report 2 rules without orphaned roles, got %+v, %v", fixed, err)
+	}
+	fixed, err = adapter.Repair(ctx, newTestModel(), RepairOptions{DryRun: true, OrphanedRoles: true})
+	if err != nil || len(fixed) != 3 {
+		t.Fatalf("Expected a dry run to report 3 rules, got %+v, %v", fixed, err)
+	}
+	if rules, _ := adapter.GetRules(ctx, Filter{}); len(rules) != 8 {
+		t.Errorf("Dry run shouldn't remove anything, got %d rules", len(rules))
+	}
+
+	fixed, err = adapter.Repair(ctx, newTestModel(), RepairOptions{Problems: []Problem{ProblemDuplicate}})
+	if err != nil || len(fixed) != 1 {
+		t.Fatalf("Expected to remove the duplicate, got %+v, %v", fixed, err)
+	}
+	if rules, _ := adapter.GetRules(ctx, Filter{V0: []string{"alice"}, Ptype: []string{"p"}}); len(rules) != 1 {
+		t.Errorf("Expected one copy of alice's policy to stay, got %d", len(rules))
+	}
+
+	if _, err := adapter.Repair(ctx, newTestModel(), RepairOptions{}); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if diagnostics, _ := adapter.Check(ctx, newTestModel()); len(diagnostics) != 1 || diagnostics[0].Problem != ProblemOrphanedRole {
+		t.Errorf("Expected the orphaned role kept by default, got %+v", diagnostics)
+	}
+	if _, err := adapter.Repair(ctx, newTestModel(), RepairOptions{OrphanedRoles: true}); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if diagnostics, _ := adapter.Check(ctx, newTestModel()); len(diagnostics) != 0 {
+		t.Errorf("Expected no problems after repairing, got %+v", diagnostics)
+	}
+	if rules, _ := adapter.GetRules(ctx, Filter{}); len(rules) != 5 {
+		t.Errorf("Expected 5 rules left, got %d", len(rules))
+	}
+
+	entries, err := adapter.AuditTrail(ctx, AuditQuery{})
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	repaired := 0
+	for _, entry := range entries {
+		if entry.Operation == AuditRepairPolicy {
+			repaired++
+		}
+	}
+	if repaired != 3 {
+		t.Errorf("Expected 3 audited removals, got %d", repaired)
+	}
+}
+
+func TestCheckEncodedRolesIntegration(t *testing.T) {
+	codec := newTestEncryptionCodec()
+	adapter := setupTestAdapter(t,
+		WithValueCodec("p", 0, codec),
+		WithValueCodec("g", 0, codec),
+		WithValueCodec("g", 1, codec),
+	)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	_ = adapter.AddPolicy("p", "p", []string{"admin", "data1", "read"})
+	_ = adapter.AddPolicy("g", "g", []string{"alice", "admin"})
+
+	// p's v1 and v2 aren't encrypted, so roles can't be compared with them
+	diagnostics, err := adapter.Check(ctx, newTestModel())
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("Expected no false orphans, got %+v", diagnostics)
+	}
+}
+
+func TestCheckMappedRolesIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t,
+		WithFieldMapping("p", "subject", "object", "action"),
+		WithValueCodec("p", 0, JSONCodec{}),
+		WithValueCodec("p", 1, JSONCodec{}),
+		WithValueCodec("p", 2, JSONCodec{}),
+		WithValueCodec("g", 0, JSONCodec{}),
+		WithValueCodec("g", 1, JSONCodec{}),
+	)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	_ = adapter.AddPolicy("p", "p", []string{"admin", "data1", "read"})
+	_ = adapter.AddPolicy("g", "g", []string{"alice", "admin"})
+	_ = adapter.AddPolicy("g", "g", []string{"bob", "ghost"})
+
+	diagnostics, err := adapter.Check(ctx, newTestModel())
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Problem != ProblemOrphanedRole || diagnostics[0].Rule.V1 != "ghost" {
+		t.Errorf("Expected only ghost to be orphaned, got %+v", diagnostics)
+	}
+}
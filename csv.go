@@ -0,0 +1,360 @@
+package arangoadapter
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb/shared"
+	"github.com/arangodb/go-driver/v2/connection"
+)
+
+// ImportMode says what ImportCSV does with the rules that are already stored.
+type ImportMode int
+
+const (
+	// ImportReplace removes every stored rule first, like SavePolicy.
+	ImportReplace ImportMode = iota
+
+	// ImportMerge adds the file's rules to the stored ones. Stored rules that are also in
+	// the file get the context's labels, if any, and keep their expiry time.
+	ImportMerge
+
+	// ImportSkipExisting adds the file's rules that aren't stored yet and leaves the rest alone.
+	ImportSkipExisting
+)
+
+// importBatchSize is how many rules ImportCSV writes per request.
+const importBatchSize = 1000
+
+// ImportCSV reads a Casbin policy file, such as policy.csv, and stores its rules according
+// to mode. Lines are parsed exactly like Casbin's file adapter does: blank lines and lines
+// starting with # are skipped, fields are comma separated with optional quotes, and each
+// line can have its own number of values. Duplicate rules are only stored once.
+//
+// The file is read as a stream and written in batches, except with ImportReplace, which reads
+// and checks the whole file before removing anything. With auditing or versioning enabled,
+// the whole import runs in one transaction; since r can't be read twice, it isn't retried.
+// It returns the number of rules added.
+func (a *Adapter) ImportCSV(ctx context.Context, r io.Reader, mode ImportMode) (imported int, err error) {
+	ctx, op := a.startWrite(ctx, "ImportCSV")
+	defer func() {
+		op.setRuleCount(imported)
+		op.end(err)
+	}()
+
+	if mode < ImportReplace || mode > ImportSkipExisting {
+		return 0, fmt.Errorf("invalid import mode %d", mode)
+	}
+
+	var lastErr error
+	attempted := false
+	err = a.recorded(ctx, func(tx *Adapter) error {
+		if attempted {
+			return fmt.Errorf("import failed and can't be retried once its input is read: %w", lastErr)
+		}
+		attempted = true
+
+		imported, lastErr = tx.importRules(ctx, r, mode)
+		return lastErr
+	})
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// importRules does the work for ImportCSV.
+func (a *Adapter) importRules(ctx context.Context, r io.Reader, mode ImportMode) (int, error) {
+	// Stored rules and the ones read so far by identity, with the keys of the stored ones
+	seen := make(map[string]string)
+	var replacing []CasbinRule
+	if mode == ImportReplace {
+		// Read everything first, so a bad line doesn't leave the stored rules half replaced
+		err := a.readCSVRules(r, func(rule CasbinRule) error {
+			replacing = append(replacing, rule)
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, name := range a.routing.names() {
+			if err := a.clearCollection(ctx, name, []string{}); err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		err := a.eachRule(ctx, a.routing.names(), notDeleted, map[string]interface{}{}, func(rule CasbinRule) error {
+			seen[rule.identity()] = rule.Key
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var added []CasbinRule
+	imported := 0
+	batch := make([]CasbinRule, 0, importBatchSize)
+	var refresh []string // Keys of stored rules to update, in merge mode
+	flush := func() error {
+		if len(refresh) > 0 {
+			if err := a.refreshRules(ctx, refresh); err != nil {
+				return err
+			}
+			refresh = refresh[:0]
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		stampCreated(ctx, batch)
+		if err := a.bulkInsert(ctx, batch); err != nil {
+			return err
+		}
+		imported += len(batch)
+		if a.recording() {
+			added = append(added, batch...)
+		}
+		batch = make([]CasbinRule, 0, importBatchSize)
+		return nil
+	}
+
+	add := func(rule CasbinRule) error {
+		identity := rule.identity()
+		key, stored := seen[identity]
+		switch {
+		case !stored:
+			batch = append(batch, rule)
+		case mode == ImportMerge && key != "":
+			refresh = append(refresh, key)
+		}
+		seen[identity] = ""
+
+		if len(batch) == importBatchSize || len(refresh) == importBatchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	if mode == ImportReplace {
+		for _, rule := range replacing {
+			if err := add(rule); err != nil {
+				return 0, err
+			}
+		}
+	} else if err := a.readCSVRules(r, add); err != nil {
+		return 0, err
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	return imported, a.record(ctx, change{operation: AuditImportPolicy, added: added, replaced: mode == ImportReplace})
+}
+
+// readCSVRules calls fn with each rule of the policy file r.
+func (a *Adapter) readCSVRules(r io.Reader, fn func(rule CasbinRule) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		rule, ok, err := a.parseCSVLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if !ok {
+			continue
+		}
+		if err := fn(rule); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseCSVLine parses a line of a policy file the way Casbin's persist.LoadPolicyLine does.
+// It reports false for blank lines and comments.
+func (a *Adapter) parseCSVLine(text string) (CasbinRule, bool, error) {
+	line := strings.TrimSpace(text)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return CasbinRule{}, false, nil
+	}
+
+	r := csv.NewReader(strings.NewReader(line))
+	r.Comma = ','
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+
+	tokens, err := r.Read()
+	if err != nil {
+		return CasbinRule{}, false, err
+	}
+	if tokens[0] == "" {
+		return CasbinRule{}, false, errors.New("missing ptype")
+	}
+	if len(tokens)-1 > len(fieldNames) {
+		return CasbinRule{}, false, fmt.Errorf("rule has %d values, at most %d are supported", len(tokens)-1, len(fieldNames))
+	}
+	return a.savePolicyLine(tokens[0], tokens[1:]), true, nil
+}
+
+// refreshRules gives the stored rules with the given keys the context's labels, if any.
+// Their expiry time stays, as the file doesn't have one.
+func (a *Adapter) refreshRules(ctx context.Context, keys []string) error {
+	updates := "updatedAt: @now"
+	bindVars := map[string]interface{}{
+		"keys": keys,
+		"now":  formatTime(time.Now()),
+	}
+	if labels := LabelsFromContext(ctx); labels != nil {
+		updates += ", labels: @labels"
+		bindVars["labels"] = labels
+	}
+	query := "FOR doc IN @@collection FILTER doc._key IN @keys && " + notDeleted +
+		" UPDATE doc WITH { " + updates + " } IN @@collection OPTIONS { mergeObjects: false }"
+
+	for _, name := range a.routing.names() {
+		bindVars["@collection"] = name
+		if _, _, err := a.writeQuery(ctx, query, bindVars, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bulkInsert stores lines through ArangoDB's import API. That API can't take part in
+// stream transactions and doesn't report document keys, so inside a transaction lines
+// are inserted like any other rules instead. The rules get their keys up front, so a
+// retried import skips the ones an earlier attempt already stored.
+func (a *Adapter) bulkInsert(ctx context.Context, lines []CasbinRule) error {
+	if a.transaction != nil {
+		return a.createRules(ctx, lines)
+	}
+
+	docs := make(map[string][]interface{})
+	var names []string
+	for _, line := range lines {
+		if line.Key == "" {
+			var err error
+			if line.Key, err = newKey(); err != nil {
+				return err
+			}
+		}
+		doc, err := a.mapping.encode(line)
+		if err != nil {
+			return err
+		}
+		name := a.routing.collectionFor(line.Ptype)
+		if _, ok := docs[name]; !ok {
+			names = append(names, name)
+		}
+		docs[name] = append(docs[name], doc)
+	}
+
+	for _, name := range names {
+		onDuplicate := "error"
+		err := a.withRetry(ctx, func() error {
+			err := a.importDocuments(ctx, name, docs[name], onDuplicate)
+
+			// Rules an earlier attempt imported are already there
+			onDuplicate = "ignore"
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importDocuments sends docs to the import API of the named collection. The import is all
+// or nothing, and onDuplicate says what happens to documents whose key is already taken.
+func (a *Adapter) importDocuments(ctx context.Context, name string, docs []interface{}, onDuplicate string) error {
+	var response struct {
+		shared.ResponseStruct `json:",inline"`
+		Created               int      `json:"created"`
+		Errors                int      `json:"errors"`
+		Details               []string `json:"details"`
+	}
+
+	url := connection.NewUrl("_db", a.databaseName, "_api", "import")
+	resp, err := connection.CallPost(ctx, a.client.Connection(), url, &response, docs,
+		connection.WithQuery("collection", name),
+		connection.WithQuery("type", "list"),
+		connection.WithQuery("complete", "true"),
+		connection.WithQuery("details", "true"),
+		connection.WithQuery("onDuplicate", onDuplicate),
+	)
+	if err != nil {
+		return err
+	}
+	if resp.Code() != http.StatusCreated {
+		return response.AsArangoErrorWithCode(resp.Code())
+	}
+	if response.Errors > 0 {
+		return fmt.Errorf("importing into %s: %s", name, strings.Join(response.Details, "; "))
+	}
+	return nil
+}
+
+// ExportCSV writes the rules matching filter to w as a Casbin policy file, one rule per line
+// in the format Casbin's file adapter writes. Values are quoted where needed so the file reads
+// back the same way. An empty filter exports every rule. Expired rules are left out.
+// It returns the number of rules written.
+func (a *Adapter) ExportCSV(ctx context.Context, w io.Writer, filter Filter) (exported int, err error) {
+	ctx, op := a.startOperation(ctx, "ExportCSV")
+	defer func() {
+		op.setRuleCount(exported)
+		op.end(err)
+	}()
+
+	conditions, bindVars, err := a.mapping.filterConditions(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	out := bufio.NewWriter(w)
+	now := formatTime(time.Now())
+	err = a.eachRule(ctx, a.routing.namesFor(filter.Ptype), conditions, bindVars, func(rule CasbinRule) error {
+		if rule.Ptype == "" || rule.expired(now) {
+			return nil
+		}
+
+		line, err := csvLine(rule.policyArray())
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", rule.Key, err)
+		}
+		if _, err := out.WriteString(line + "\n"); err != nil {
+			return err
+		}
+		exported++
+		return nil
+	})
+	if err != nil {
+		return exported, err
+	}
+	return exported, out.Flush()
+}
+
+// csvLine joins fields with ", " like Casbin's file adapter, quoting the ones that wouldn't
+// read back the same way otherwise.
+func csvLine(fields []string) (string, error) {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		// Policy files are read line by line, so there's no way to store a line break
+		if strings.ContainsAny(field, "\r\n") {
+			return "", errors.New("values with line breaks can't be written to a policy file")
+		}
+		if strings.ContainsAny(field, `,"`) || strings.TrimSpace(field) != field || (i == 0 && strings.HasPrefix(field, "#")) {
+			field = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+		quoted[i] = field
+	}
+	return strings.Join(quoted, ", "), nil
+}
@@ -0,0 +1,155 @@
+package arangoadapter
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSVLine(t *testing.T) {
+	a := &Adapter{}
+	tests := map[string][]string{
+		"p, alice, data1, read":          {"p", "alice", "data1", "read"},
+		"  g, alice, admin  ":            {"g", "alice", "admin"},
+		`p, alice, "data1, data2", read`: {"p", "alice", "data1, data2", "read"},
+		`p, "say ""hi""", data1`:         {"p", `say "hi"`, "data1"},
+		"p, alice, , read":               {"p", "alice", "", "read"},
+		"p2, a, b, c, d, e, f":           {"p2", "a", "b", "c", "d", "e", "f"},
+	}
+	for line, expected := range tests {
+		rule, ok, err := a.parseCSVLine(line)
+		if err != nil || !ok {
+			t.Errorf("Failed to parse %q: %v", line, err)
+			continue
+		}
+		if got := rule.policyArray(); strings.Join(got, "|") != strings.Join(expected, "|") {
+			t.Errorf("Parsing %q gave %q", line, got)
+		}
+	}
+
+	for _, line := range []string{"", "   ", "# comment", "  # indented comment"} {
+		if _, ok, err := a.parseCSVLine(line); ok || err != nil {
+			t.Errorf("Expected %q to be skipped, got %v", line, err)
+		}
+	}
+
+	for _, line := range []string{`p, "unterminated`, "p, a, b, c, d, e, f, g", ", alice"} {
+		if _, _, err := a.parseCSVLine(line); err == nil {
+			t.Errorf("Expected an error for %q", line)
+		}
+	}
+}
+
+func TestCSVLine(t *testing.T) {
+	a := &Adapter{}
+	for _, fields := range [][]string{
+		{"p", "alice", "data1", "read"},
+		{"p", "alice", "data1, data2", "read"},
+		{"p", `say "hi"`, " padded "},
+		{"p", "alice", "", "read"},
+	} {
+		line, err := csvLine(fields)
+		if err != nil {
+			t.Fatalf("Failed to write %q: %v", fields, err)
+		}
+		rule, _, err := a.parseCSVLine(line)
+		if err != nil {
+			t.Fatalf("Failed to read back %q: %v", line, err)
+		}
+		if got := rule.policyArray(); strings.Join(got, "|") != strings.Join(fields, "|") {
+			t.Errorf("%q read back as %q", line, got)
+		}
+	}
+
+	if line, _ := csvLine([]string{"p", "alice", "data1", "read"}); line != "p, alice, data1, read" {
+		t.Errorf("Plain values should be written like Casbin does, got %q", line)
+	}
+	if _, err := csvLine([]string{"p", "two\nlines"}); err == nil {
+		t.Error("Line breaks should be rejected")
+	}
+}
+
+func TestImportExportCSV(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	policy := `# seed policy
+p, alice, data1, read
+p, bob, "data2, archive", write
+
+g, alice, admin
+p, alice, data1, read
+`
+	imported, err := adapter.ImportCSV(ctx, strings.NewReader(policy), ImportReplace)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if imported != 3 {
+		t.Errorf("Expected 3 rules imported, got %d", imported)
+	}
+
+	more := "p, alice, data1, read\np, carol, data3, read\n"
+	if imported, err = adapter.ImportCSV(ctx, strings.NewReader(more), ImportSkipExisting); err != nil || imported != 1 {
+		t.Errorf("Expected only carol's rule added, got %d (%v)", imported, err)
+	}
+	if imported, err = adapter.ImportCSV(ctx, strings.NewReader(more), ImportMerge); err != nil || imported != 0 {
+		t.Errorf("Expected nothing added, got %d (%v)", imported, err)
+	}
+
+	var out bytes.Buffer
+	exported, err := adapter.ExportCSV(ctx, &out, Filter{Ptype: []string{"p"}})
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if exported != 3 || !strings.Contains(out.String(), `p, bob, "data2, archive", write`) {
+		t.Errorf("Unexpected export (%d rules):\n%s", exported, out.String())
+	}
+
+	// Replacing with the export leaves only the p rules
+	if _, err := adapter.ImportCSV(ctx, &out, ImportReplace); err != nil {
+		t.Fatalf("Failed to re-import: %v", err)
+	}
+	m := newTestModel()
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if groupings, _ := m.GetPolicy("g", "g"); len(groupings) != 0 {
+		t.Errorf("Expected groupings replaced, got %v", groupings)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"bob", "data2, archive", "write"}); !ok {
+		t.Error("Quoted value should survive the round trip")
+	}
+
+	if _, err := adapter.ImportCSV(ctx, strings.NewReader("p, \"broken\n"), ImportMerge); err == nil {
+		t.Error("Malformed line should fail the import")
+	}
+
+	// A bad line further down leaves the stored rules alone
+	if _, err := adapter.ImportCSV(ctx, strings.NewReader("p, mallory, data1, read\np, \"broken\n"), ImportReplace); err == nil {
+		t.Error("Malformed line should fail the import")
+	}
+	if rules, _ := adapter.GetRules(ctx, Filter{}); len(rules) != 3 {
+		t.Errorf("Expected the stored rules untouched, got %v", rules)
+	}
+}
+
+func TestImportMergeKeepsExpiryIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour)
+	if err := adapter.AddPolicyWithExpiry(ctx, "p", "p", []string{"alice", "data1", "read"}, expiresAt); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if _, err := adapter.ImportCSV(ctx, strings.NewReader("p, alice, data1, read\n"), ImportMerge); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	rules, _ := adapter.GetRules(ctx, Filter{})
+	if len(rules) != 1 || rules[0].ExpiresAt != formatTime(expiresAt) {
+		t.Errorf("Expected the rule to keep expiring, got %+v", rules)
+	}
+}
@@ -0,0 +1,145 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// Problem is the kind of issue a Diagnostic reports.
+type Problem string
+
+const (
+	ProblemInvalidDocument Problem = "invalid_document" // The stored document can't be decoded into a rule
+	ProblemMissingPtype    Problem = "missing_ptype"    // The rule has no ptype
+	ProblemUnknownPtype    Problem = "unknown_ptype"    // The model doesn't define the rule's ptype
+	ProblemArity           Problem = "arity"            // The rule has a different number of values than the model expects
+	ProblemEmptyField      Problem = "empty_field"      // One of the values the model expects is empty
+	ProblemDuplicate       Problem = "duplicate"        // An earlier rule has the same ptype and values
+	ProblemOrphanedRole    Problem = "orphaned_role"    // A grouping rule's role has no policies and no parent role (Check only)
+)
+
+// Diagnostic describes a problem with one rule. A rule can have more than one.
+type Diagnostic struct {
+	Rule    CasbinRule // The rule, with its document key if it's stored
+	Problem Problem
+	Message string // What's wrong, e.g. "has 2 values, the model expects 3"
+}
+
+// ValidateRules checks rules against m and returns a diagnostic for every problem found,
+// in the order of rules. Rules with problems can't be loaded into m, apart from empty
+// fields and duplicates, which Casbin accepts but are almost always mistakes.
+func ValidateRules(m model.Model, rules []CasbinRule) []Diagnostic {
+	v := newValidator(m)
+	for _, rule := range rules {
+		v.check(rule)
+	}
+	return v.diagnostics
+}
+
+// ValidatePolicy checks every stored rule against m, like ValidateRules. Documents that
+// can't be decoded are reported as ProblemInvalidDocument rather than failing the check.
+func (a *Adapter) ValidatePolicy(ctx context.Context, m model.Model) (diagnostics []Diagnostic, err error) {
+	ctx, op := a.startOperation(ctx, "ValidatePolicy")
+	checked := 0
+	defer func() {
+		op.setRuleCount(checked)
+		op.end(err)
+	}()
+
+	v := newValidator(m)
+	query := "FOR doc IN @@collection FILTER " + notDeleted + " RETURN doc"
+	for _, name := range a.routing.names() {
+		bindVars := map[string]interface{}{"@collection": name}
+		err := a.readDocuments(ctx, query, bindVars, func(raw json.RawMessage) error {
+			checked++
+			rule, err := a.mapping.decode(raw)
+			if err != nil {
+				var doc struct {
+					Key string `json:"_key"`
+				}
+				_ = json.Unmarshal(raw, &doc)
+				v.report(CasbinRule{Key: doc.Key}, ProblemInvalidDocument, err.Error())
+				return nil
+			}
+			v.check(rule)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v.diagnostics, nil
+}
+
+// validator collects the diagnostics for a set of rules.
+type validator struct {
+	model       model.Model
+	seen        map[string]string // Keys of the rules checked so far, by identity
+	diagnostics []Diagnostic
+}
+
+// newValidator returns a validator checking rules against m.
+func newValidator(m model.Model) *validator {
+	return &validator{model: m, seen: make(map[string]string)}
+}
+
+// report adds a diagnostic for rule.
+func (v *validator) report(rule CasbinRule, problem Problem, format string, args ...interface{}) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Rule:    rule,
+		Problem: problem,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// check validates one rule.
+func (v *validator) check(rule CasbinRule) {
+	if rule.Ptype == "" {
+		v.report(rule, ProblemMissingPtype, "missing ptype")
+		return
+	}
+	sec, ok := ptypeSection(v.model, rule.Ptype)
+	if !ok {
+		v.report(rule, ProblemUnknownPtype, "ptype %q isn't defined in the model", rule.Ptype)
+		return
+	}
+
+	// Casbin sees the values without trailing empty ones
+	values := rule.policyArray()[1:]
+	tokens := v.model[sec][rule.Ptype].Tokens
+	switch {
+	case sec == "p" && len(values) != len(tokens):
+		v.report(rule, ProblemArity, "has %d values, the model expects %d", len(values), len(tokens))
+	case sec == "g" && len(values) < len(tokens):
+		v.report(rule, ProblemArity, "has %d values, the model expects at least %d", len(values), len(tokens))
+	}
+	for i := 0; i < len(values) && i < len(tokens); i++ {
+		if values[i] == "" {
+			v.report(rule, ProblemEmptyField, "v%d (%s) is empty", i, tokens[i])
+		}
+	}
+
+	identity := rule.identity()
+	if first, ok := v.seen[identity]; ok {
+		if first == "" {
+			first = "an earlier rule"
+		}
+		v.report(rule, ProblemDuplicate, "duplicate of %s", first)
+		return
+	}
+	v.seen[identity] = rule.Key
+}
+
+// ptypeSection returns the model section defining ptype. Casbin names ptypes after their
+// section, but looking them up doesn't rely on it.
+func ptypeSection(m model.Model, ptype string) (string, bool) {
+	for _, sec := range []string{"p", "g"} {
+		if _, ok := m[sec][ptype]; ok {
+			return sec, true
+		}
+	}
+	return "", false
+}
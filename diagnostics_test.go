@@ -0,0 +1,72 @@
+package arangoadapter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValidateRules(t *testing.T) {
+	m := newTestModel()
+	m.AddDef("g", "g2", "_, _, _")
+
+	diagnostics := ValidateRules(m, []CasbinRule{
+		{Key: "1", Ptype: "p", V0: "alice", V1: "data1", V2: "read"},
+		{Key: "2", Ptype: "p", V0: "alice", V1: "data1", V2: "read"},
+		{Key: "3", Ptype: "p", V0: "bob", V1: "data1"},
+		{Key: "4", Ptype: "p2", V0: "bob", V1: "data1", V2: "read"},
+		{Key: "5", Ptype: "p", V0: "carol", V2: "read"},
+		{Key: "6"},
+		{Key: "7", Ptype: "g2", V0: "alice", V1: "admin", V2: "tenant1"},
+		{Key: "8", Ptype: "g2", V0: "alice", V1: "admin"},
+	})
+
+	expected := []struct {
+		key     string
+		problem Problem
+	}{
+		{"2", ProblemDuplicate},
+		{"3", ProblemArity},
+		{"4", ProblemUnknownPtype},
+		{"5", ProblemEmptyField},
+		{"6", ProblemMissingPtype},
+		{"8", ProblemArity},
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %+v", len(expected), diagnostics)
+	}
+	for i, e := range expected {
+		if d := diagnostics[i]; d.Rule.Key != e.key || d.Problem != e.problem || d.Message == "" {
+			t.Errorf("Expected %s for rule %s, got %+v", e.problem, e.key, d)
+		}
+	}
+}
+
+func TestValidatePolicyIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = adapter.AddPolicy("g", "g", []string{"alice", "admin"})
+	if _, err := adapter.collection.CreateDocument(ctx, map[string]interface{}{"ptype": "p", "v0": 42}); err != nil {
+		t.Fatalf("Failed to insert document: %v", err)
+	}
+	if _, err := adapter.collection.CreateDocument(ctx, map[string]interface{}{"ptype": "p3", "v0": "bob"}); err != nil {
+		t.Fatalf("Failed to insert document: %v", err)
+	}
+
+	diagnostics, err := adapter.ValidatePolicy(ctx, newTestModel())
+	if err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	problems := make(map[Problem]int)
+	for _, d := range diagnostics {
+		if d.Rule.Key == "" {
+			t.Errorf("Diagnostics for stored rules should have keys: %+v", d)
+		}
+		problems[d.Problem]++
+	}
+	if len(diagnostics) != 2 || problems[ProblemInvalidDocument] != 1 || problems[ProblemUnknownPtype] != 1 {
+		t.Errorf("Unexpected diagnostics: %+v", diagnostics)
+	}
+}
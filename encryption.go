@@ -0,0 +1,183 @@
+package arangoadapter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrKeyNotFound is returned by key providers that don't know the requested key.
+var ErrKeyNotFound = errors.New("encryption key not found")
+
+// encryptedPrefix marks encrypted values, which are stored as "enc:<key id>:<base64 data>".
+const encryptedPrefix = "enc:"
+
+// KeyProvider hands out the keys an EncryptionCodec encrypts and decrypts with.
+// Keys must be 16, 24 or 32 bytes long, for AES-128, AES-192 or AES-256.
+type KeyProvider interface {
+	// CurrentKey returns the key new values are encrypted with, and its ID.
+	CurrentKey() (id string, key []byte, err error)
+
+	// Key returns the key with the given ID, for values encrypted with an older key.
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider holding its keys in memory, e.g. loaded from the environment.
+// Keep old keys around after rotating so stored values can still be decrypted.
+type StaticKeys struct {
+	CurrentID string            // ID of the key to encrypt with
+	Keys      map[string][]byte // Keys by ID
+}
+
+// CurrentKey returns the key named by CurrentID.
+func (s StaticKeys) CurrentKey() (string, []byte, error) {
+	key, err := s.Key(s.CurrentID)
+	return s.CurrentID, key, err
+}
+
+// Key returns the key with the given ID.
+func (s StaticKeys) Key(id string) ([]byte, error) {
+	key, ok := s.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, id)
+	}
+	return key, nil
+}
+
+// EncryptionCodec encrypts rule values with AES-GCM before they're stored. Encryption is
+// deterministic: the nonce is derived from the value itself, so equal values encrypt to
+// equal ciphertexts under the same key and exact matches keep working in AQL. This reveals
+// which rules share a value, but nothing about the value itself.
+//
+// Values that aren't encrypted, e.g. stored before encryption was turned on, load as they
+// are. After rotating keys, save the policy again so every value is encrypted with the
+// current key, as lookups only match values encrypted with it.
+type EncryptionCodec struct {
+	keys    KeyProvider
+	ciphers sync.Map // *valueCipher by cipherKey
+}
+
+// cipherKey identifies a cached cipher by the key's ID and a hash of the key itself, so a
+// provider can hand out new key bytes under an existing ID.
+type cipherKey struct {
+	id   string
+	hash [sha256.Size]byte
+}
+
+// NewEncryptionCodec returns a codec encrypting with the keys from keys.
+// Register it for the positions to encrypt with WithValueCodec.
+func NewEncryptionCodec(keys KeyProvider) *EncryptionCodec {
+	return &EncryptionCodec{keys: keys}
+}
+
+// valueCipher holds the keys derived from one encryption key.
+type valueCipher struct {
+	aead     cipher.AEAD
+	nonceKey []byte // Derives nonces from values
+}
+
+// Encode encrypts value with the current key. Empty values stay empty, since they mean
+// a position isn't used.
+func (c *EncryptionCodec) Encode(value string) (interface{}, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	id, key, err := c.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if id == "" || strings.Contains(id, ":") {
+		return nil, fmt.Errorf("invalid encryption key ID %q", id)
+	}
+	vc, err := c.cipher(id, key)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, vc.nonceKey)
+	mac.Write([]byte(value))
+	nonce := mac.Sum(nil)[:vc.aead.NonceSize()]
+
+	data := vc.aead.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + id + ":" + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decode decrypts a stored value, returning values that aren't encrypted as they are.
+func (c *EncryptionCodec) Decode(stored interface{}) (string, error) {
+	s, ok := stored.(string)
+	if !ok {
+		return JSONCodec{}.Decode(stored)
+	}
+	if !strings.HasPrefix(s, encryptedPrefix) {
+		return s, nil
+	}
+
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(s, encryptedPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+
+	key, err := c.keys.Key(id)
+	if err != nil {
+		return "", err
+	}
+	vc, err := c.cipher(id, key)
+	if err != nil {
+		return "", err
+	}
+
+	size := vc.aead.NonceSize()
+	if len(data) < size {
+		return "", errors.New("malformed encrypted value")
+	}
+	value, err := vc.aead.Open(nil, data[:size], data[size:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypting value with key %q: %w", id, err)
+	}
+	return string(value), nil
+}
+
+// cipher returns the cipher for a key, setting it up on first use.
+func (c *EncryptionCodec) cipher(id string, key []byte) (*valueCipher, error) {
+	cacheKey := cipherKey{id: id, hash: sha256.Sum256(key)}
+	if vc, ok := c.ciphers.Load(cacheKey); ok {
+		return vc.(*valueCipher), nil
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("encryption key %q must be 16, 24 or 32 bytes long", id)
+	}
+
+	// Separate keys for encrypting and deriving nonces, both taken from the same key
+	block, err := aes.NewCipher(deriveKey(key, "encryption")[:len(key)])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	vc, _ := c.ciphers.LoadOrStore(cacheKey, &valueCipher{aead: aead, nonceKey: deriveKey(key, "nonce")})
+	return vc.(*valueCipher), nil
+}
+
+// deriveKey derives a 32 byte key for purpose from key.
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("arangoadapter " + purpose))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,191 @@
+package arangoadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestEncryptionCodec() *EncryptionCodec {
+	return NewEncryptionCodec(StaticKeys{
+		CurrentID: "k2",
+		Keys: map[string][]byte{
+			"k1": bytes.Repeat([]byte{1}, 32),
+			"k2": bytes.Repeat([]byte{2}, 32),
+		},
+	})
+}
+
+func TestEncryptionCodec(t *testing.T) {
+	codec := newTestEncryptionCodec()
+
+	stored, err := codec.Encode("customer-42")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	s := stored.(string)
+	if !strings.HasPrefix(s, "enc:k2:") || strings.Contains(s, "customer-42") {
+		t.Errorf("Unexpected encrypted value: %s", s)
+	}
+
+	// Deterministic, so exact matches work
+	again, _ := codec.Encode("customer-42")
+	if again != stored {
+		t.Error("Equal values should encrypt the same way")
+	}
+	other, _ := codec.Encode("customer-43")
+	if other == stored {
+		t.Error("Different values should encrypt differently")
+	}
+
+	value, err := codec.Decode(stored)
+	if err != nil || value != "customer-42" {
+		t.Errorf("Expected customer-42, got %q (%v)", value, err)
+	}
+
+	if empty, _ := codec.Encode(""); empty != "" {
+		t.Errorf("Empty values should stay empty, got %v", empty)
+	}
+	if plain, _ := codec.Decode("alice"); plain != "alice" {
+		t.Errorf("Plaintext values should load as they are, got %q", plain)
+	}
+}
+
+func TestEncryptionCodecKeys(t *testing.T) {
+	old := NewEncryptionCodec(StaticKeys{CurrentID: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}})
+	stored, _ := old.Encode("customer-42")
+
+	// Values encrypted with an older key still decrypt after rotating
+	if value, err := newTestEncryptionCodec().Decode(stored); err != nil || value != "customer-42" {
+		t.Errorf("Expected customer-42, got %q (%v)", value, err)
+	}
+
+	missing := NewEncryptionCodec(StaticKeys{CurrentID: "k3", Keys: map[string][]byte{"k3": bytes.Repeat([]byte{3}, 32)}})
+	if _, err := missing.Decode(stored); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	// Same ID, different key: authentication fails
+	wrong := NewEncryptionCodec(StaticKeys{CurrentID: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{9}, 32)}})
+	if _, err := wrong.Decode(stored); err == nil {
+		t.Error("Decrypting with the wrong key should fail")
+	}
+
+	short := NewEncryptionCodec(StaticKeys{CurrentID: "k1", Keys: map[string][]byte{"k1": []byte("short")}})
+	if _, err := short.Encode("customer-42"); err == nil {
+		t.Error("Invalid key length should be rejected")
+	}
+}
+
+func TestEncryptionCodecReplacedKey(t *testing.T) {
+	keys := map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}
+	codec := NewEncryptionCodec(StaticKeys{CurrentID: "k1", Keys: keys})
+	before, _ := codec.Encode("customer-42")
+
+	// The provider hands out new bytes under the same ID
+	keys["k1"] = bytes.Repeat([]byte{7}, 32)
+	after, err := codec.Encode("customer-42")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if after == before {
+		t.Error("Expected the new key to be used, not a cached cipher for the old one")
+	}
+	if _, err := codec.Decode(before); err == nil {
+		t.Error("Values encrypted with the replaced key shouldn't decrypt with the new one")
+	}
+	if value, err := codec.Decode(after); err != nil || value != "customer-42" {
+		t.Errorf("Expected customer-42, got %q (%v)", value, err)
+	}
+}
+
+func TestEncryptedAuditAndHistoryIntegration(t *testing.T) {
+	codec := newTestEncryptionCodec()
+	adapter := setupTestAdapter(t, WithValueCodec("p", 0, codec), WithAudit(""), WithVersioning(""))
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	if err := adapter.AddPolicy("p", "p", []string{"customer-42", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	added := time.Now()
+
+	for _, name := range []string{adapter.auditCollectionName, adapter.historyCollectionName} {
+		cursor, err := adapter.query(ctx, "FOR doc IN @@collection RETURN doc", map[string]interface{}{"@collection": name})
+		if err != nil {
+			t.Fatalf("Failed to query %s: %v", name, err)
+		}
+		for cursor.HasMore() {
+			var raw json.RawMessage
+			_, _ = cursor.ReadDocument(ctx, &raw)
+			if strings.Contains(string(raw), "customer-42") {
+				t.Errorf("Expected %s to hold the value encrypted, got %s", name, raw)
+			}
+		}
+		_ = cursor.Close()
+	}
+
+	entries, err := adapter.AuditTrail(ctx, AuditQuery{Subject: "customer-42"})
+	if err != nil {
+		t.Fatalf("Failed to read audit trail: %v", err)
+	}
+	if len(entries) != 1 || entries[0].NewRule == nil || entries[0].NewRule.V0 != "customer-42" {
+		t.Errorf("Expected the entry decrypted, got %+v", entries)
+	}
+
+	m := newTestModel()
+	if err := adapter.LoadPolicyAt(ctx, m, added); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"customer-42", "data1", "read"}); !ok {
+		t.Error("Expected the revision decrypted")
+	}
+}
+
+func TestEncryptionIntegration(t *testing.T) {
+	codec := newTestEncryptionCodec()
+	adapter := setupTestAdapter(t, WithValueCodec("p", 0, codec), WithValueCodec("g", 0, codec))
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	_ = adapter.AddPolicy("p", "p", []string{"customer-42", "data1", "read"})
+	_ = adapter.AddPolicy("g", "g", []string{"customer-42", "admin"})
+
+	var raw map[string]interface{}
+	cursor, err := adapter.query(ctx, "FOR doc IN @@collection FILTER doc.ptype == 'p' RETURN doc", map[string]interface{}{
+		"@collection": adapter.collectionName,
+	})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	_, _ = cursor.ReadDocument(ctx, &raw)
+	_ = cursor.Close()
+	if v0, _ := raw["v0"].(string); !strings.HasPrefix(v0, "enc:") {
+		t.Errorf("Expected v0 stored encrypted, got %v", raw["v0"])
+	}
+
+	m := newTestModel()
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"customer-42", "data1", "read"}); !ok {
+		t.Error("Encrypted value should load decrypted")
+	}
+
+	m = newTestModel()
+	if err := adapter.LoadFilteredPolicy(m, Filter{V0: []string{"customer-42"}}); err != nil {
+		t.Fatalf("Failed to load filtered policy: %v", err)
+	}
+	if ok, _ := m.HasPolicy("g", "g", []string{"customer-42", "admin"}); !ok {
+		t.Error("Filter should match encrypted values")
+	}
+
+	count, err := adapter.RemovePolicyCount(ctx, "p", "p", []string{"customer-42", "data1", "read"})
+	if err != nil || count != 1 {
+		t.Errorf("Expected the encrypted rule removed, got %d (%v)", count, err)
+	}
+}
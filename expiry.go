@@ -0,0 +1,114 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb"
+)
+
+// notExpired matches documents without an expiry time or one after the @now bind var.
+const notExpired = `(doc.expiresAt IN [null, ""] OR doc.expiresAt > @now)`
+
+// ensureExpiryIndex adds the TTL index that lets ArangoDB delete rules once their
+// expiresAt time has passed, unless col is known to have it. Rules without expiresAt are
+// never touched by it.
+func (a *Adapter) ensureExpiryIndex(ctx context.Context, col arangodb.Collection) error {
+	if _, ok := a.expiryIndexed.Load(col.Name()); ok {
+		return nil
+	}
+	if _, _, err := col.EnsureTTLIndex(ctx, []string{"expiresAt"}, 0, nil); err != nil {
+		return err
+	}
+	a.expiryIndexed.Store(col.Name(), true)
+	return nil
+}
+
+// expired reports whether the rule's expiry time is at or before now.
+// ArangoDB reaps expired documents in the background, so they can linger for a while.
+func (r CasbinRule) expired(now string) bool {
+	return r.ExpiresAt != "" && r.ExpiresAt <= now
+}
+
+// identity returns a string that's the same for rules with the same ptype and values.
+func (r CasbinRule) identity() string {
+	return strings.Join(append([]string{r.Ptype}, r.values()...), "\x00")
+}
+
+// AddPolicyWithExpiry adds a policy rule that stops applying at expiresAt.
+// Expired rules are skipped when loading and deleted by ArangoDB's TTL index shortly after,
+// which is added to the rule's collection first if it isn't there yet (see WithExpiry).
+// With WithExpiryHook the index is left out, so that the watcher gets to see expired rules.
+// The rule goes straight to the database, so reload the enforcer's policy to pick it up.
+func (a *Adapter) AddPolicyWithExpiry(ctx context.Context, sec string, ptype string, rule []string, expiresAt time.Time) (err error) {
+	ctx, op := a.startWrite(ctx, "AddPolicyWithExpiry", ruleAttrs(sec, ptype, 1)...)
+	defer func() { op.end(err) }()
+
+	if a.stopExpiryWatcher == nil {
+		if err := a.ensureExpiryIndex(ctx, a.collections[a.routing.collectionFor(ptype)]); err != nil {
+			return err
+		}
+	}
+	line := a.savePolicyLine(ptype, rule)
+	line.ExpiresAt = formatTime(expiresAt)
+	return a.addRules(ctx, []CasbinRule{line})
+}
+
+// RemoveExpiredPolicies deletes every rule whose expiry time has passed and returns them.
+// The TTL index does this on its own eventually; removing them here also records the
+// removals in the audit log and revision history.
+func (a *Adapter) RemoveExpiredPolicies(ctx context.Context) (expired []CasbinRule, err error) {
+	ctx, op := a.startWrite(ctx, "RemoveExpiredPolicies")
+	defer func() {
+		op.setRuleCount(len(expired))
+		op.end(err)
+	}()
+
+	bindVars := map[string]interface{}{
+		"now": formatTime(time.Now()),
+	}
+	expired, _, err = a.removeRules(ctx, AuditExpirePolicy, a.routing.names(), "doc.expiresAt != null && doc.expiresAt <= @now", bindVars, true)
+	if errors.Is(err, ErrRuleNotFound) {
+		// Nothing having expired isn't a failure
+		return nil, nil
+	}
+	return expired, err
+}
+
+// startExpiryWatcher removes expired rules every interval and hands them to hook
+// until Close is called. A non-positive interval checks once a minute.
+func (a *Adapter) startExpiryWatcher(interval time.Duration, hook func(expired []CasbinRule)) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopExpiryWatcher = cancel
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			expired, err := a.RemoveExpiredPolicies(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					a.logger.WarnContext(ctx, "failed to remove expired rules", slog.Any("error", err))
+				}
+				continue
+			}
+			if len(expired) > 0 {
+				a.logger.InfoContext(ctx, "removed expired rules", slog.Int("count", len(expired)))
+				hook(expired)
+			}
+		}
+	}()
+}
@@ -0,0 +1,158 @@
+package arangoadapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb"
+)
+
+func TestCasbinRuleExpired(t *testing.T) {
+	now := formatTime(time.Now())
+
+	if (CasbinRule{}).expired(now) {
+		t.Error("Rule without expiry shouldn't expire")
+	}
+	if !(CasbinRule{ExpiresAt: formatTime(time.Now().Add(-time.Minute))}).expired(now) {
+		t.Error("Rule that expired a minute ago should be expired")
+	}
+	if (CasbinRule{ExpiresAt: formatTime(time.Now().Add(time.Hour))}).expired(now) {
+		t.Error("Rule expiring in an hour shouldn't be expired")
+	}
+}
+
+func TestCasbinRuleIdentity(t *testing.T) {
+	a := CasbinRule{Ptype: "p", V0: "alice", V1: "data1", V2: "read"}
+	b := CasbinRule{Key: "123", Ptype: "p", V0: "alice", V1: "data1", V2: "read", ExpiresAt: "2030-01-01T00:00:00.000Z"}
+	if a.identity() != b.identity() {
+		t.Error("Identity should only depend on ptype and values")
+	}
+
+	c := CasbinRule{Ptype: "p", V0: "alice", V1: "data1read"}
+	if a.identity() == c.identity() {
+		t.Error("Identity shouldn't mix up values across fields")
+	}
+}
+
+func TestAddPolicyWithExpiry(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := context.Background()
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+	if err := adapter.AddPolicyWithExpiry(ctx, "p", "p", []string{"oncall", "prod", "admin"}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Failed to add expiring policy: %v", err)
+	}
+	if err := adapter.AddPolicyWithExpiry(ctx, "p", "p", []string{"bob", "data2", "write"}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to add expiring policy: %v", err)
+	}
+
+	m := newTestModel()
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"oncall", "prod", "admin"}); ok {
+		t.Error("Expired rule shouldn't be loaded")
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"bob", "data2", "write"}); !ok {
+		t.Error("Rule that hasn't expired yet should be loaded")
+	}
+
+	// Saving the model must not turn bob's temporary rule into a permanent one
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+	removed, err := adapter.RemoveFilteredPolicyReturning(ctx, "p", "p", 0, "bob")
+	if err != nil || len(removed) != 1 {
+		t.Fatalf("Expected bob's rule to be stored once, got %v (%v)", removed, err)
+	}
+	if removed[0].ExpiresAt == "" {
+		t.Error("SavePolicy should keep the expiry time of existing rules")
+	}
+}
+
+func TestRemoveExpiredPolicies(t *testing.T) {
+	adapter := setupTestAdapter(t, WithAudit(""))
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := context.Background()
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+	_ = adapter.AddPolicyWithExpiry(ctx, "p", "p", []string{"oncall", "prod", "admin"}, time.Now().Add(-time.Second))
+
+	expired, err := adapter.RemoveExpiredPolicies(ctx)
+	if err != nil {
+		t.Fatalf("Failed to remove expired policies: %v", err)
+	}
+	if len(expired) != 1 || expired[0].V0 != "oncall" {
+		t.Fatalf("Expected the on-call rule to expire, got %v", expired)
+	}
+
+	entries, _ := adapter.AuditTrail(ctx, AuditQuery{Subject: "oncall"})
+	if len(entries) != 2 || entries[1].Operation != AuditExpirePolicy {
+		t.Errorf("Expected the expiry in the audit log, got %+v", entries)
+	}
+
+	if expired, err := adapter.RemoveExpiredPolicies(ctx); err != nil || len(expired) != 0 {
+		t.Errorf("Nothing else should expire, got %v (%v)", expired, err)
+	}
+}
+
+func TestExpiryHook(t *testing.T) {
+	fired := make(chan []CasbinRule, 1)
+	adapter := setupTestAdapter(t, WithExpiryHook(50*time.Millisecond, func(expired []CasbinRule) {
+		fired <- expired
+	}))
+	defer teardownTestAdapter(t, adapter)
+	defer func() { _ = adapter.Close() }()
+
+	_ = adapter.AddPolicyWithExpiry(context.Background(), "p", "p", []string{"oncall", "prod", "admin"}, time.Now().Add(100*time.Millisecond))
+
+	select {
+	case expired := <-fired:
+		if len(expired) != 1 || expired[0].V0 != "oncall" {
+			t.Errorf("Expected the on-call rule, got %v", expired)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expiry hook didn't fire")
+	}
+
+	indexes, err := adapter.collection.Indexes(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list indexes: %v", err)
+	}
+	for _, index := range indexes {
+		if index.Type == arangodb.TTLIndexType {
+			t.Error("The TTL index shouldn't be added when a hook removes expired rules")
+		}
+	}
+}
+
+func TestExpiryIndexIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := context.Background()
+	hasTTLIndex := func() bool {
+		indexes, err := adapter.collection.Indexes(ctx)
+		if err != nil {
+			t.Fatalf("Failed to list indexes: %v", err)
+		}
+		for _, index := range indexes {
+			if index.Type == arangodb.TTLIndexType {
+				return true
+			}
+		}
+		return false
+	}
+
+	if hasTTLIndex() {
+		t.Error("TTL index shouldn't be added before any rule expires")
+	}
+	if err := adapter.AddPolicyWithExpiry(ctx, "p", "p", []string{"alice", "data1", "read"}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if !hasTTLIndex() {
+		t.Error("Expected the TTL index after adding an expiring rule")
+	}
+}
@@ -0,0 +1,312 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// ErrVersioningDisabled is returned by LoadPolicyAt and RestorePolicyAt when the adapter
+// was created without WithVersioning.
+var ErrVersioningDisabled = errors.New("policy versioning is not enabled")
+
+// revision is one version of a rule in the history collection. The rule was live from
+// ValidFrom until ValidTo, which stays null as long as it still exists.
+type revision struct {
+	RuleKey   string      `json:"ruleKey"` // Key of the rule's document in the policy collection
+	Rule      interface{} `json:"rule"`    // The rule, encoded like in the policy collection
+	ValidFrom string      `json:"validFrom"`
+	ValidTo   *string     `json:"validTo"`
+}
+
+// ensureHistoryCollection gets or creates the history collection and makes sure every rule
+// in the policy collection has an open revision, holding the schema lock while it checks.
+// An empty name defaults to the policy collection name with a "_history" suffix.
+func (a *Adapter) ensureHistoryCollection(name string) error {
+	if name == "" {
+		name = a.collectionName + "_history"
+	}
+
+	ctx := context.Background()
+	col, err := a.ensureCollection(ctx, name, nil)
+	if err != nil {
+		return err
+	}
+
+	a.historyCollection = col
+	a.historyCollectionName = name
+	if a.readOnly {
+		return nil
+	}
+
+	// Closing revisions looks them up by rule, point-in-time loads by start time
+	if _, _, err := col.EnsurePersistentIndex(ctx, []string{"ruleKey", "validTo"}, nil); err != nil {
+		return err
+	}
+	if _, _, err := col.EnsurePersistentIndex(ctx, []string{"validFrom"}, nil); err != nil {
+		return err
+	}
+
+	// Adapters starting at the same time would open the same revisions twice
+	if a.metaCollection != nil {
+		release, err := a.lockSchema(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	return a.inTransaction(ctx, func(tx *Adapter) error {
+		return tx.syncHistory(ctx)
+	})
+}
+
+// syncHistory brings the open revisions in line with the policy collection, which matters
+// when versioning is switched on for existing rules or writes happened without it.
+// It runs in a transaction, so writes going on meanwhile can't be half accounted for.
+// Revisions only know the rule's key, so keys are assumed to be unique across rule collections.
+func (a *Adapter) syncHistory(ctx context.Context) error {
+	bindVars := map[string]interface{}{
+		"@history": a.historyCollectionName,
+		"now":      formatTime(time.Now()),
+	}
+
+	// Close revisions of rules that were removed behind our back
+	lookups := make([]string, 0, len(a.collections))
+	for i, name := range a.routing.names() {
+		param := fmt.Sprintf("collection%d", i)
+		bindVars["@"+param] = name
+		lookups = append(lookups, "DOCUMENT(@@"+param+", r.ruleKey)")
+	}
+	cursor, err := a.query(ctx, `FOR r IN @@history FILTER r.validTo == null
+		LET live = (FOR doc IN [`+strings.Join(lookups, ", ")+`] FILTER doc != null && doc.deletedAt == null RETURN 1)
+		FILTER LENGTH(live) == 0
+		UPDATE r WITH { validTo: @now } IN @@history`, bindVars)
+	if err != nil {
+		return err
+	}
+	_ = cursor.Close()
+
+	// Open revisions for rules that don't have one
+	query := `LET open = (FOR r IN @@history FILTER r.validTo == null RETURN r.ruleKey)
+		FOR doc IN @@collection FILTER doc._key NOT IN open && doc.deletedAt == null
+		RETURN doc`
+	var missing []CasbinRule
+	for _, name := range a.routing.names() {
+		bindVars := map[string]interface{}{
+			"@collection": name,
+			"@history":    a.historyCollectionName,
+		}
+		err := a.readRules(ctx, query, bindVars, func(rule CasbinRule) error {
+			missing = append(missing, rule)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return a.recordHistory(ctx, change{added: missing})
+}
+
+// recordHistory closes the revisions of the rules c removed and opens new ones for the
+// rules it added. It does nothing when versioning is disabled.
+func (a *Adapter) recordHistory(ctx context.Context, c change) error {
+	if a.historyCollection == nil {
+		return nil
+	}
+	now := formatTime(time.Now())
+
+	if c.replaced {
+		var err error
+		if c.added, err = a.replaceHistory(ctx, c.added, now); err != nil {
+			return err
+		}
+	} else if len(c.removed) > 0 {
+		keys := make([]string, 0, len(c.removed))
+		for _, rule := range c.removed {
+			keys = append(keys, rule.Key)
+		}
+		cursor, err := a.query(ctx, "FOR r IN @@history FILTER r.validTo == null FILTER r.ruleKey IN @keys UPDATE r WITH { validTo: @now } IN @@history", map[string]interface{}{
+			"@history": a.historyCollectionName,
+			"keys":     keys,
+			"now":      now,
+		})
+		if err != nil {
+			return err
+		}
+		_ = cursor.Close()
+	}
+
+	if len(c.added) == 0 {
+		return nil
+	}
+	revisions := make([]revision, 0, len(c.added))
+	for _, rule := range c.added {
+		key := rule.Key
+		rule.Key = ""
+		doc, err := a.mapping.encode(rule)
+		if err != nil {
+			return err
+		}
+		revisions = append(revisions, revision{RuleKey: key, Rule: doc, ValidFrom: now})
+	}
+	_, err := a.historyCollection.CreateDocuments(ctx, revisions)
+	return err
+}
+
+// replaceHistory brings the open revisions in line with rules after the whole collection
+// was replaced, comparing rules by identity. Rules that didn't change keep their revision,
+// pointed at their new document key if they got one, and revisions of rules that are gone
+// are closed. It returns the rules that need a new revision.
+func (a *Adapter) replaceHistory(ctx context.Context, rules []CasbinRule, now string) ([]CasbinRule, error) {
+	type openRevision struct {
+		Key     string          `json:"_key"`
+		RuleKey string          `json:"ruleKey"`
+		Rule    json.RawMessage `json:"rule"`
+	}
+
+	open := make(map[string][]openRevision)
+	bindVars := map[string]interface{}{"@history": a.historyCollectionName}
+	err := a.readDocuments(ctx, "FOR r IN @@history FILTER r.validTo == null RETURN r", bindVars, func(raw json.RawMessage) error {
+		var r openRevision
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return err
+		}
+		rule, err := a.mapping.decode(r.Rule)
+		if err != nil {
+			return err
+		}
+		open[rule.identity()] = append(open[rule.identity()], r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var added []CasbinRule
+	var moved []map[string]string
+	for _, rule := range rules {
+		revisions := open[rule.identity()]
+		if len(revisions) == 0 {
+			added = append(added, rule)
+			continue
+		}
+		open[rule.identity()] = revisions[1:]
+		if revisions[0].RuleKey != rule.Key {
+			moved = append(moved, map[string]string{"key": revisions[0].Key, "ruleKey": rule.Key})
+		}
+	}
+	var closed []string
+	for _, revisions := range open {
+		for _, r := range revisions {
+			closed = append(closed, r.Key)
+		}
+	}
+
+	if len(closed) > 0 {
+		cursor, err := a.query(ctx, "FOR key IN @keys UPDATE key WITH { validTo: @now } IN @@history", map[string]interface{}{
+			"@history": a.historyCollectionName,
+			"keys":     closed,
+			"now":      now,
+		})
+		if err != nil {
+			return nil, err
+		}
+		_ = cursor.Close()
+	}
+	if len(moved) > 0 {
+		cursor, err := a.query(ctx, "FOR m IN @moved UPDATE m.key WITH { ruleKey: m.ruleKey } IN @@history", map[string]interface{}{
+			"@history": a.historyCollectionName,
+			"moved":    moved,
+		})
+		if err != nil {
+			return nil, err
+		}
+		_ = cursor.Close()
+	}
+	return added, nil
+}
+
+// LoadPolicyAt loads the policy as it was at the given time into the model.
+// It requires the adapter to be created with WithVersioning, and only knows about
+// changes made since versioning was enabled.
+func (a *Adapter) LoadPolicyAt(ctx context.Context, model model.Model, at time.Time) (err error) {
+	ctx, op := a.startOperation(ctx, "LoadPolicyAt")
+	loaded := 0
+	defer func() {
+		op.setRuleCount(loaded)
+		op.end(err)
+	}()
+
+	if a.historyCollectionName == "" {
+		return ErrVersioningDisabled
+	}
+
+	rules, err := a.rulesAt(ctx, at)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := loadPolicyLine(rule, model); err != nil {
+			return err
+		}
+		loaded++
+	}
+	return nil
+}
+
+// RestorePolicyAt replaces the policy collection with the rules that were live at the
+// given time, keeping their original document keys. The restore itself is versioned too,
+// so it can be undone by restoring a later point. Reload the enforcer's policy afterwards.
+func (a *Adapter) RestorePolicyAt(ctx context.Context, at time.Time) (err error) {
+	ctx, op := a.startWrite(ctx, "RestorePolicyAt")
+	restored := 0
+	defer func() {
+		op.setRuleCount(restored)
+		op.end(err)
+	}()
+
+	if a.historyCollectionName == "" {
+		return ErrVersioningDisabled
+	}
+
+	return a.recorded(ctx, func(tx *Adapter) error {
+		rules, err := tx.rulesAt(ctx, at)
+		if err != nil {
+			return err
+		}
+		if err := tx.replaceRules(ctx, rules); err != nil {
+			return err
+		}
+		restored = len(rules)
+		return tx.record(ctx, change{operation: AuditRestorePolicy, added: rules, replaced: true})
+	})
+}
+
+// rulesAt returns the rules that were live at the given time, with their document keys.
+// Rules that had already expired by then are left out.
+func (a *Adapter) rulesAt(ctx context.Context, at time.Time) ([]CasbinRule, error) {
+	query := `FOR r IN @@history
+		FILTER r.validFrom <= @at AND (r.validTo == null OR r.validTo > @at)
+		FILTER r.rule.expiresAt IN [null, ""] OR r.rule.expiresAt > @at
+		SORT r.validFrom
+		RETURN MERGE(r.rule, { _key: r.ruleKey })`
+	bindVars := map[string]interface{}{
+		"@history": a.historyCollectionName,
+		"at":       formatTime(at),
+	}
+
+	var rules []CasbinRule
+	err := a.readRules(ctx, query, bindVars, func(rule CasbinRule) error {
+		rules = append(rules, rule)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,182 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChangeAuditEntries(t *testing.T) {
+	update := change{
+		operation: AuditUpdatePolicy,
+		removed:   []CasbinRule{{Key: "1", Ptype: "p", V0: "alice", V2: "read"}},
+		added:     []CasbinRule{{Key: "1", Ptype: "p", V0: "alice", V2: "write"}},
+	}
+	entries := update.auditEntries()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry per updated rule, got %d", len(entries))
+	}
+	if entries[0].OldRule.V2 != "read" || entries[0].NewRule.V2 != "write" {
+		t.Errorf("Update entry should pair old and new rule: %+v", entries[0])
+	}
+	if entries[0].OldRule.Key != "" || entries[0].NewRule.Key != "" {
+		t.Error("Audit entries shouldn't carry document keys")
+	}
+
+	save := change{operation: AuditSavePolicy, added: make([]CasbinRule, 3), replaced: true}
+	entries = save.auditEntries()
+	if len(entries) != 1 || entries[0].RuleCount != 3 {
+		t.Errorf("Replacing everything should be one entry with the rule count, got %+v", entries)
+	}
+}
+
+func TestLoadPolicyAt(t *testing.T) {
+	adapter := setupTestAdapter(t, WithVersioning(""))
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := context.Background()
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data2", "write"})
+
+	time.Sleep(10 * time.Millisecond)
+	before := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	if err := adapter.UpdatePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	if err := adapter.RemovePolicyCtx(ctx, "p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("Failed to remove policy: %v", err)
+	}
+
+	m := newTestModel()
+	if err := adapter.LoadPolicyAt(ctx, m, before); err != nil {
+		t.Fatalf("Failed to load policy at %v: %v", before, err)
+	}
+	policies, _ := m.GetPolicy("p", "p")
+	if len(policies) != 2 {
+		t.Fatalf("Expected 2 policies before the changes, got %v", policies)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "data1", "read"}); !ok {
+		t.Error("Alice's original rule should be loaded")
+	}
+
+	m = newTestModel()
+	if err := adapter.LoadPolicyAt(ctx, m, time.Now()); err != nil {
+		t.Fatalf("Failed to load current policy: %v", err)
+	}
+	policies, _ = m.GetPolicy("p", "p")
+	if len(policies) != 1 || policies[0][2] != "write" {
+		t.Errorf("Expected only the updated rule now, got %v", policies)
+	}
+}
+
+func TestLoadPolicyAtSkipsExpiredRules(t *testing.T) {
+	adapter := setupTestAdapter(t, WithVersioning(""))
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := context.Background()
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	if err := adapter.AddPolicyWithExpiry(ctx, "p", "p", []string{"oncall", "prod", "admin"}, expiresAt); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	m := newTestModel()
+	if err := adapter.LoadPolicyAt(ctx, m, expiresAt.Add(-10*time.Millisecond)); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"oncall", "prod", "admin"}); !ok {
+		t.Error("The rule should apply before it expires")
+	}
+
+	m = newTestModel()
+	if err := adapter.LoadPolicyAt(ctx, m, expiresAt.Add(time.Second)); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if policies, _ := m.GetPolicy("p", "p"); len(policies) != 0 {
+		t.Errorf("Expired rules shouldn't be loaded, got %v", policies)
+	}
+}
+
+func TestRestorePolicyAt(t *testing.T) {
+	adapter := setupTestAdapter(t, WithVersioning(""))
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := context.Background()
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+
+	time.Sleep(10 * time.Millisecond)
+	before := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	m := newTestModel()
+	_ = m.AddPolicy("p", "p", []string{"mallory", "data1", "write"})
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+
+	if err := adapter.RestorePolicyAt(ctx, before); err != nil {
+		t.Fatalf("Failed to restore policy: %v", err)
+	}
+
+	m = newTestModel()
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	policies, _ := m.GetPolicy("p", "p")
+	if len(policies) != 1 || policies[0][0] != "alice" {
+		t.Errorf("Expected only alice's rule after restoring, got %v", policies)
+	}
+}
+
+func TestVersioningDisabled(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+
+	if err := adapter.LoadPolicyAt(context.Background(), newTestModel(), time.Now()); !errors.Is(err, ErrVersioningDisabled) {
+		t.Errorf("Expected ErrVersioningDisabled, got %v", err)
+	}
+	if err := adapter.RestorePolicyAt(context.Background(), time.Now()); !errors.Is(err, ErrVersioningDisabled) {
+		t.Errorf("Expected ErrVersioningDisabled, got %v", err)
+	}
+}
+
+func TestSavePolicyKeepsUnchangedRevisions(t *testing.T) {
+	adapter := setupTestAdapter(t, WithVersioning(""))
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := context.Background()
+	m := newTestModel()
+	_ = m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = m.AddPolicy("p", "p", []string{"bob", "data2", "write"})
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+
+	m = newTestModel()
+	_ = m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = m.AddPolicy("p", "p", []string{"bob", "data2", "read"})
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+
+	count := func(query string) int64 {
+		var n int64
+		err := adapter.readDocuments(ctx, query, map[string]interface{}{"@history": adapter.historyCollectionName}, func(raw json.RawMessage) error {
+			return json.Unmarshal(raw, &n)
+		})
+		if err != nil {
+			t.Fatalf("Failed to count revisions: %v", err)
+		}
+		return n
+	}
+	// alice's revision carries on, bob's old one is closed and his new one opened
+	if n := count("FOR r IN @@history COLLECT WITH COUNT INTO n RETURN n"); n != 3 {
+		t.Errorf("Expected 3 revisions, got %d", n)
+	}
+	if n := count("FOR r IN @@history FILTER r.validTo == null COLLECT WITH COUNT INTO n RETURN n"); n != 2 {
+		t.Errorf("Expected 2 open revisions, got %d", n)
+	}
+}
@@ -0,0 +1,137 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// errMissingPtype is reported for stored documents without a ptype.
+var errMissingPtype = errors.New("rule has no ptype")
+
+// SkippedRule is a stored rule a lenient load left out, and why.
+type SkippedRule struct {
+	Key  string     // Document key
+	Rule CasbinRule // The rule, empty apart from the key if its document couldn't be decoded
+	Err  error      // Why it couldn't be loaded
+}
+
+// LoadPolicyLenient is like LoadPolicyCtx, but rules that can't be loaded into model, e.g.
+// because model doesn't define their ptype or they have the wrong number of values, are
+// skipped and returned instead of failing the load. Documents without a ptype are reported
+// too. It only fails on database errors.
+func (a *Adapter) LoadPolicyLenient(ctx context.Context, model model.Model) ([]SkippedRule, error) {
+	return a.loadPolicy(ctx, model, true)
+}
+
+// loadPolicy loads every rule into model, see LoadPolicyCtx and LoadPolicyLenient.
+func (a *Adapter) loadPolicy(ctx context.Context, model model.Model, lenient bool) (skipped []SkippedRule, err error) {
+	ctx, op := a.startOperation(ctx, "LoadPolicy")
+	loaded := 0
+	defer func() {
+		op.setRuleCount(loaded)
+		if err == nil {
+			a.metrics.ObserveRulesLoaded(loaded)
+			a.refreshCollectionSize(ctx)
+		}
+		op.end(err)
+	}()
+
+	loaded, skipped, err = a.loadRules(ctx, model, a.routing.names(), notDeleted, map[string]interface{}{}, lenient)
+	return skipped, err
+}
+
+// loadRules loads the rules matching conditions in the named collections into model, leaving
+// out expired ones. In lenient mode, rules that can't be loaded are skipped and returned.
+func (a *Adapter) loadRules(ctx context.Context, model model.Model, names []string, conditions string, bindVars map[string]interface{}, lenient bool) (int, []SkippedRule, error) {
+	loaded := 0
+	var skipped []SkippedRule
+	now := formatTime(time.Now())
+	query := "FOR doc IN @@collection FILTER " + conditions + " RETURN doc"
+	for _, name := range names {
+		bindVars["@collection"] = name
+		err := a.readDocuments(ctx, query, bindVars, func(raw json.RawMessage) error {
+			rule, err := a.mapping.decode(raw)
+			if err != nil {
+				if !lenient {
+					return err
+				}
+				var doc struct {
+					Key string `json:"_key"`
+				}
+				_ = json.Unmarshal(raw, &doc)
+				skipped = append(skipped, a.skipRule(ctx, CasbinRule{Key: doc.Key}, fmt.Errorf("decoding rule: %w", err)))
+				return nil
+			}
+
+			// Skip rules that have expired but haven't been reaped yet
+			if rule.expired(now) {
+				return nil
+			}
+			if rule.Ptype == "" {
+				if lenient {
+					skipped = append(skipped, a.skipRule(ctx, rule, errMissingPtype))
+				}
+				return nil
+			}
+
+			if err := loadPolicyLine(rule, model); err != nil {
+				if !lenient {
+					return err
+				}
+				skipped = append(skipped, a.skipRule(ctx, rule, err))
+				return nil
+			}
+			loaded++
+			return nil
+		})
+		if err != nil {
+			return loaded, skipped, err
+		}
+	}
+	return loaded, skipped, nil
+}
+
+// skipRule logs a rule left out of a lenient load and returns it as a SkippedRule.
+func (a *Adapter) skipRule(ctx context.Context, rule CasbinRule, err error) SkippedRule {
+	a.logger.WarnContext(ctx, "skipped invalid rule",
+		slog.String("key", rule.Key),
+		slog.String("ptype", rule.Ptype),
+		slog.Any("error", err),
+	)
+	return SkippedRule{Key: rule.Key, Rule: rule, Err: err}
+}
+
+// readDocuments runs query and calls fn with every document it returns, undecoded.
+func (a *Adapter) readDocuments(ctx context.Context, query string, bindVars map[string]interface{}, fn func(raw json.RawMessage) error) error {
+	cursor, err := a.query(ctx, query, bindVars)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
+
+	for cursor.HasMore() {
+		var raw json.RawMessage
+		if _, err := cursor.ReadDocument(ctx, &raw); err != nil {
+			return err
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportSkipped passes the rules a lenient load skipped to the hook set with WithLenientLoad.
+func (a *Adapter) reportSkipped(skipped []SkippedRule) {
+	if a.onSkipped != nil && len(skipped) > 0 {
+		a.onSkipped(skipped)
+	}
+}
@@ -0,0 +1,96 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// insertInvalidRules stores documents that can't be loaded into the test model, returning their keys.
+func insertInvalidRules(t *testing.T, adapter *Adapter) map[string]bool {
+	ctx := context.Background()
+	docs := []map[string]interface{}{
+		{"ptype": "p9", "v0": "alice", "v1": "data1", "v2": "read"}, // Not in the model
+		{"ptype": "p", "v0": "alice", "v1": "data1"},                // Missing a value
+		{"v0": "nobody"},                    // No ptype
+		{"ptype": "p", "v0": 42, "v1": "x"}, // Not a string
+	}
+
+	keys := make(map[string]bool)
+	for _, doc := range docs {
+		meta, err := adapter.collection.CreateDocument(ctx, doc)
+		if err != nil {
+			t.Fatalf("Failed to insert document: %v", err)
+		}
+		keys[meta.Key] = true
+	}
+	return keys
+}
+
+func TestLoadPolicyLenient(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = adapter.AddPolicy("g", "g", []string{"alice", "admin"})
+	invalid := insertInvalidRules(t, adapter)
+
+	// Strict is the default
+	if err := adapter.LoadPolicy(newTestModel()); err == nil {
+		t.Error("Expected the default load to fail on invalid rules")
+	}
+
+	m := newTestModel()
+	skipped, err := adapter.LoadPolicyLenient(ctx, m)
+	if err != nil {
+		t.Fatalf("Lenient load failed: %v", err)
+	}
+	if len(skipped) != len(invalid) {
+		t.Fatalf("Expected %d skipped rules, got %+v", len(invalid), skipped)
+	}
+	for _, s := range skipped {
+		if !invalid[s.Key] || s.Err == nil {
+			t.Errorf("Unexpected skipped rule: %+v", s)
+		}
+		if s.Rule.Ptype == "" && s.Rule.V0 == "nobody" && !errors.Is(s.Err, errMissingPtype) {
+			t.Errorf("Expected errMissingPtype, got %v", s.Err)
+		}
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "data1", "read"}); !ok {
+		t.Error("Valid policy should be loaded")
+	}
+	if ok, _ := m.HasPolicy("g", "g", []string{"alice", "admin"}); !ok {
+		t.Error("Valid grouping should be loaded")
+	}
+}
+
+func TestWithLenientLoad(t *testing.T) {
+	var reported []SkippedRule
+	adapter := setupTestAdapter(t, WithLenientLoad(func(skipped []SkippedRule) {
+		reported = append(reported, skipped...)
+	}))
+	defer teardownTestAdapter(t, adapter)
+
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	invalid := insertInvalidRules(t, adapter)
+
+	if err := adapter.LoadPolicy(newTestModel()); err != nil {
+		t.Fatalf("Lenient load failed: %v", err)
+	}
+	if len(reported) != len(invalid) {
+		t.Errorf("Expected the hook to get %d rules, got %d", len(invalid), len(reported))
+	}
+
+	reported = nil
+	m := newTestModel()
+	if err := adapter.LoadFilteredPolicy(m, Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatalf("Lenient filtered load failed: %v", err)
+	}
+	if len(reported) != 2 {
+		t.Errorf("Expected the filtered load to skip the two invalid alice rules, got %+v", reported)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "data1", "read"}); !ok {
+		t.Error("Valid policy should be loaded")
+	}
+}
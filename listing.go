@@ -0,0 +1,244 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb"
+)
+
+// ErrInvalidCursor is returned by ListRules for a cursor it didn't hand out, or one from a
+// listing with a different sort order.
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// Page selects a page of ListRules results.
+type Page struct {
+	Size       int    // Rules per page (default 100, at most 1000)
+	Cursor     string // RulePage.Next of the previous page, empty for the first page
+	SortBy     string // Rule field to sort by, "v0" to "v5", or empty for document key order
+	Descending bool
+}
+
+// RulePage is a page of ListRules results.
+type RulePage struct {
+	Rules []CasbinRule // The rules, with their document keys
+	Next  string       // Cursor for the next page, empty on the last one
+	Total int64        // Rules matching the filter on all pages
+}
+
+// pageCursor is what a page cursor encodes: where the previous page stopped, and how it
+// was sorted.
+type pageCursor struct {
+	SortBy     string          `json:"s,omitempty"`
+	Descending bool            `json:"d,omitempty"`
+	Value      json.RawMessage `json:"v,omitempty"` // Stored sort value of the last rule
+	Key        string          `json:"k"`           // Key of the last rule
+}
+
+// ListRules returns a page of the rules matching filter, for building admin screens on top
+// of the collection. Pages are sorted by page.SortBy with the document key breaking ties,
+// and the cursor picks up after the last rule of the previous page, so pages stay stable
+// while rules are added or removed and don't get slower further in. Values are sorted as
+// stored. Expired rules are left out.
+//
+// Add sort indexes with WithSortIndexes to keep sorting fast on large collections.
+func (a *Adapter) ListRules(ctx context.Context, filter Filter, page Page) (result *RulePage, err error) {
+	ctx, op := a.startOperation(ctx, "ListRules")
+	defer func() {
+		if result != nil {
+			op.setRuleCount(len(result.Rules))
+		}
+		op.end(err)
+	}()
+
+	size := page.Size
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	size = min(size, maxPageSize)
+
+	sortExpr := "doc._key"
+	field := -1
+	if page.SortBy != "" {
+		field = fieldIndex(page.SortBy)
+		if field < 0 {
+			return nil, fmt.Errorf("can't sort by %q, expected v0 to v5", page.SortBy)
+		}
+		sortExpr = a.mapping.anyFieldExpr(field)
+	}
+
+	conditions, bindVars, err := a.mapping.filterConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+	conditions += " AND " + notExpired
+	bindVars["now"] = formatTime(time.Now())
+	names := a.routing.namesFor(filter.Ptype)
+
+	result = &RulePage{}
+	if result.Total, err = a.countRules(ctx, names, conditions, bindVars); err != nil {
+		return nil, err
+	}
+
+	order, after := "ASC", ">"
+	if page.Descending {
+		order, after = "DESC", "<"
+	}
+	if page.Cursor != "" {
+		cursor, err := decodePageCursor(page.Cursor)
+		if err != nil || cursor.SortBy != page.SortBy || cursor.Descending != page.Descending {
+			return nil, ErrInvalidCursor
+		}
+		if field < 0 {
+			conditions += " AND doc._key " + after + " @after_key"
+		} else {
+			// Named after the field, so query logging redacts it like the field itself
+			value := "@after_" + fieldNames[field]
+			conditions += " AND (" + sortExpr + " " + after + " " + value +
+				" OR (" + sortExpr + " == " + value + " AND doc._key " + after + " @after_key))"
+			bindVars["after_"+fieldNames[field]] = cursor.Value
+		}
+		bindVars["after_key"] = cursor.Key
+	}
+
+	// Each collection returns its first rules after the cursor, and the page is the first
+	// of those, so rules from different collections are sorted by ArangoDB the same way
+	subqueries := make([]string, len(names))
+	for i, name := range names {
+		collection := fmt.Sprintf("@@collection%d", i)
+		subqueries[i] = "(FOR doc IN " + collection + " FILTER " + conditions +
+			" SORT " + sortExpr + " " + order + ", doc._key " + order +
+			" LIMIT @limit RETURN { doc, sort: " + sortExpr + " })"
+		bindVars[collection[1:]] = name
+	}
+	query := "FOR item IN FLATTEN([" + strings.Join(subqueries, ", ") + "])" +
+		" SORT item.sort " + order + ", item.doc._key " + order +
+		" LIMIT @limit RETURN item"
+	bindVars["limit"] = size + 1
+
+	var last pageCursor
+	err = a.readDocuments(ctx, query, bindVars, func(raw json.RawMessage) error {
+		var item struct {
+			Doc  json.RawMessage `json:"doc"`
+			Sort json.RawMessage `json:"sort"`
+		}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return err
+		}
+		if len(result.Rules) == size {
+			// There's at least one more rule, so there's a next page
+			result.Next = encodePageCursor(last)
+			return nil
+		}
+
+		rule, err := a.mapping.decode(item.Doc)
+		if err != nil {
+			return err
+		}
+		result.Rules = append(result.Rules, rule)
+		last = pageCursor{SortBy: page.SortBy, Descending: page.Descending, Key: rule.Key}
+		if field >= 0 {
+			last.Value = item.Sort
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CountRules returns how many stored rules match filter, like the Total of ListRules, without
+// loading them. Expired rules ArangoDB hasn't deleted yet aren't counted. An empty filter
+// counts every rule.
+func (a *Adapter) CountRules(ctx context.Context, filter Filter) (count int64, err error) {
+	ctx, op := a.startOperation(ctx, "CountRules")
+	defer func() {
+		op.setRuleCount(int(count))
+		op.end(err)
+	}()
+
+	conditions, bindVars, err := a.mapping.filterConditions(filter)
+	if err != nil {
+		return 0, err
+	}
+	conditions += " AND " + notExpired
+	bindVars["now"] = formatTime(time.Now())
+	return a.countRules(ctx, a.routing.namesFor(filter.Ptype), conditions, bindVars)
+}
+
+// countRules counts the rules matching conditions in the named collections.
+func (a *Adapter) countRules(ctx context.Context, names []string, conditions string, bindVars map[string]interface{}) (int64, error) {
+	query := "FOR doc IN @@collection FILTER " + conditions + " COLLECT WITH COUNT INTO n RETURN n"
+	var total int64
+	for _, name := range names {
+		vars := make(map[string]interface{}, len(bindVars)+1)
+		for k, v := range bindVars {
+			vars[k] = v
+		}
+		vars["@collection"] = name
+		err := a.readDocuments(ctx, query, vars, func(raw json.RawMessage) error {
+			var n int64
+			if err := json.Unmarshal(raw, &n); err != nil {
+				return err
+			}
+			total += n
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// ensureSortIndexes adds the indexes set with WithSortIndexes. They're on the default value
+// attributes, so they don't help with ptypes that have a field mapping.
+func (a *Adapter) ensureSortIndexes(ctx context.Context, col arangodb.Collection) error {
+	for _, name := range a.sortIndexes {
+		if fieldIndex(name) < 0 {
+			return fmt.Errorf("can't index %q for sorting, expected v0 to v5", name)
+		}
+		if _, _, err := col.EnsurePersistentIndex(ctx, []string{name, "_key"}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldIndex returns the position of a rule field like "v1", or -1 if name isn't one.
+func fieldIndex(name string) int {
+	for i, field := range fieldNames {
+		if field == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// encodePageCursor turns c into an opaque cursor string.
+func encodePageCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageCursor reads a cursor string made by encodePageCursor.
+func decodePageCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
@@ -0,0 +1,114 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPageCursor(t *testing.T) {
+	c := pageCursor{SortBy: "v1", Descending: true, Value: []byte(`"data1"`), Key: "123"}
+	decoded, err := decodePageCursor(encodePageCursor(c))
+	if err != nil {
+		t.Fatalf("Failed to decode cursor: %v", err)
+	}
+	if decoded.SortBy != c.SortBy || decoded.Descending != c.Descending || string(decoded.Value) != string(c.Value) || decoded.Key != c.Key {
+		t.Errorf("Expected %+v, got %+v", c, decoded)
+	}
+
+	if _, err := decodePageCursor("not a cursor!"); err == nil {
+		t.Error("Expected an error for a malformed cursor")
+	}
+}
+
+func TestFieldIndex(t *testing.T) {
+	for name, want := range map[string]int{"v0": 0, "v5": 5, "v6": -1, "ptype": -1, "": -1} {
+		if got := fieldIndex(name); got != want {
+			t.Errorf("fieldIndex(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestListRulesIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t, WithSortIndexes("v1"))
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	var rules [][]string
+	for i := 0; i < 25; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%02d", i), fmt.Sprintf("data%02d", 24-i), "read"})
+	}
+	if err := adapter.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("Failed to add grouping: %v", err)
+	}
+
+	filter := Filter{Ptype: []string{"p"}}
+	page := Page{Size: 10, SortBy: "v1"}
+	var objects []string
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("Expected the listing to end after 3 pages")
+		}
+		result, err := adapter.ListRules(ctx, filter, page)
+		if err != nil {
+			t.Fatalf("Failed to list rules: %v", err)
+		}
+		if result.Total != 25 {
+			t.Errorf("Expected a total of 25, got %d", result.Total)
+		}
+		for _, rule := range result.Rules {
+			if rule.Key == "" {
+				t.Error("Expected listed rules to have keys")
+			}
+			objects = append(objects, rule.V1)
+		}
+		if result.Next == "" {
+			break
+		}
+		page.Cursor = result.Next
+	}
+
+	if len(objects) != 25 {
+		t.Fatalf("Expected 25 rules over all pages, got %d", len(objects))
+	}
+	for i, object := range objects {
+		if want := fmt.Sprintf("data%02d", i); object != want {
+			t.Errorf("Expected rule %d to be on %s, got %s", i, want, object)
+		}
+	}
+
+	page.Descending = true
+	if _, err := adapter.ListRules(ctx, filter, page); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor for a cursor with another sort order, got %v", err)
+	}
+	if _, err := adapter.ListRules(ctx, filter, Page{SortBy: "ptype"}); err == nil {
+		t.Error("Expected an error sorting by a field that isn't a rule value")
+	}
+}
+
+func TestCountRulesIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = adapter.AddPolicy("p", "p", []string{"bob", "data1", "write"})
+	_ = adapter.AddPolicy("g", "g", []string{"alice", "admin"})
+
+	if count, err := adapter.CountRules(ctx, Filter{}); err != nil || count != 3 {
+		t.Errorf("Expected 3 rules, got %d, %v", count, err)
+	}
+	if count, err := adapter.CountRules(ctx, Filter{Ptype: []string{"p"}, V1: []string{"data1"}}); err != nil || count != 2 {
+		t.Errorf("Expected 2 matching rules, got %d, %v", count, err)
+	}
+
+	_ = adapter.AddPolicyWithExpiry(ctx, "p", "p", []string{"carol", "data1", "read"}, time.Now().Add(-time.Second))
+	if count, err := adapter.CountRules(ctx, Filter{}); err != nil || count != 3 {
+		t.Errorf("Expected the expired rule not to be counted, got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,66 @@
+package arangoadapter
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// redacted replaces bind variable values that must not end up in logs.
+const redacted = "[REDACTED]"
+
+// discardHandler drops every record. It's the default so the adapter stays silent.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// newLogger returns the configured logger tagged with the database and collection,
+// or a logger that discards everything.
+func newLogger(cfg *Config) *slog.Logger {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.New(discardHandler{})
+	}
+	return logger.With(
+		slog.String("database", cfg.DatabaseName),
+		slog.String("collection", cfg.CollectionName),
+	)
+}
+
+// logQuery logs an AQL query and its bind variables at debug level when query logging is enabled.
+// Values of the fields listed in WithQueryLogging are redacted.
+func (a *Adapter) logQuery(ctx context.Context, query string, bindVars map[string]interface{}) {
+	if !a.logQueries || !a.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	safe := make(map[string]interface{}, len(bindVars))
+	for name, value := range bindVars {
+		if a.redacted(name) {
+			value = redacted
+		}
+		safe[name] = value
+	}
+
+	a.logger.DebugContext(ctx, "executing query",
+		slog.String("aql", query),
+		slog.Any("bindVars", safe),
+	)
+}
+
+// redacted reports whether the bind variable holds a field that must not be logged.
+// Prefixed variables such as new_v0 count as the field they're named after.
+func (a *Adapter) redacted(name string) bool {
+	if i := strings.LastIndex(name, "_"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, field := range a.redactFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
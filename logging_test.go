@@ -0,0 +1,63 @@
+package arangoadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestLogQueryRedactsFields(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewConfig(
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		WithQueryLogging("v1"),
+	)
+	a := &Adapter{
+		logger:       newLogger(cfg),
+		logQueries:   cfg.LogQueries,
+		redactFields: cfg.RedactFields,
+	}
+
+	a.logQuery(context.Background(), "FOR doc IN @@collection RETURN doc", map[string]interface{}{
+		"@collection": "casbin_rule",
+		"v0":          "alice",
+		"v1":          "customer-1234",
+		"new_v1":      "customer-5678",
+	})
+
+	var entry struct {
+		Msg        string            `json:"msg"`
+		AQL        string            `json:"aql"`
+		Collection string            `json:"collection"`
+		BindVars   map[string]string `json:"bindVars"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse log output %q: %v", buf.String(), err)
+	}
+
+	if entry.AQL != "FOR doc IN @@collection RETURN doc" {
+		t.Errorf("Unexpected AQL in log: %q", entry.AQL)
+	}
+	if entry.Collection != defaultCollectionName {
+		t.Errorf("Expected collection attribute, got %q", entry.Collection)
+	}
+	if entry.BindVars["v0"] != "alice" {
+		t.Errorf("v0 should be logged as-is, got %q", entry.BindVars["v0"])
+	}
+	if entry.BindVars["v1"] != redacted || entry.BindVars["new_v1"] != redacted {
+		t.Errorf("v1 should be redacted, got %v", entry.BindVars)
+	}
+}
+
+func TestLogQueryDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewConfig(WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	a := &Adapter{logger: newLogger(cfg)}
+
+	a.logQuery(context.Background(), "FOR doc IN @@collection RETURN doc", nil)
+	if buf.Len() != 0 {
+		t.Errorf("Queries shouldn't be logged without WithQueryLogging: %s", buf.String())
+	}
+}
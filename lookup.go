@@ -0,0 +1,64 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errFound stops a lookup at the first match.
+var errFound = errors.New("found")
+
+// HasRule reports whether the exact rule is stored, like Casbin's HasPolicy but without
+// loading the policy. Soft deleted and expired rules don't count.
+func (a *Adapter) HasRule(ctx context.Context, ptype string, rule []string) (found bool, err error) {
+	ctx, op := a.startOperation(ctx, "HasRule", attrPtype.String(ptype))
+	defer func() { op.end(err) }()
+
+	line := a.savePolicyLine(ptype, rule)
+	conditions, bindVars, err := a.mapping.ruleConditions(line)
+	if err != nil {
+		return false, err
+	}
+
+	// The conditions skip empty values, so check the rest of the rule here
+	identity := line.identity()
+	now := formatTime(time.Now())
+	err = a.eachRule(ctx, []string{a.routing.collectionFor(ptype)}, "("+conditions+") && "+notDeleted, bindVars, func(stored CasbinRule) error {
+		if stored.identity() == identity && !stored.expired(now) {
+			return errFound
+		}
+		return nil
+	})
+	if errors.Is(err, errFound) {
+		return true, nil
+	}
+	return false, err
+}
+
+// FindRules returns the stored rules matching a partial filter starting at fieldIndex, the
+// rules RemoveFilteredPolicyCtx would remove. Expired rules are left out.
+func (a *Adapter) FindRules(ctx context.Context, ptype string, fieldIndex int, fieldValues ...string) (rules []CasbinRule, err error) {
+	ctx, op := a.startOperation(ctx, "FindRules", attrPtype.String(ptype))
+	defer func() {
+		op.setRuleCount(len(rules))
+		op.end(err)
+	}()
+
+	conditions, bindVars, err := a.mapping.filteredConditions(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return nil, err
+	}
+
+	now := formatTime(time.Now())
+	err = a.eachRule(ctx, []string{a.routing.collectionFor(ptype)}, "("+conditions+") && "+notDeleted, bindVars, func(rule CasbinRule) error {
+		if !rule.expired(now) {
+			rules = append(rules, rule)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,66 @@
+package arangoadapter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHasRuleAndFindRulesIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t, WithSoftDelete())
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	rules := [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data1", "write"},
+		{"alice", "data2", "read"},
+		{"bob", "data1", "read"},
+	}
+	if err := adapter.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	if err := adapter.RemovePolicy("p", "p", []string{"bob", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to remove policy: %v", err)
+	}
+
+	tests := []struct {
+		rule []string
+		want bool
+	}{
+		{[]string{"alice", "data1", "read"}, true},
+		{[]string{"alice", "data1"}, false}, // A prefix of stored rules isn't a match
+		{[]string{"alice", "data3", "read"}, false},
+		{[]string{"bob", "data1", "read"}, false}, // Soft deleted
+	}
+	for _, tt := range tests {
+		found, err := adapter.HasRule(ctx, "p", tt.rule)
+		if err != nil {
+			t.Fatalf("Failed to look up %v: %v", tt.rule, err)
+		}
+		if found != tt.want {
+			t.Errorf("HasRule(%v) = %v, want %v", tt.rule, found, tt.want)
+		}
+	}
+
+	found, err := adapter.FindRules(ctx, "p", 1, "data1")
+	if err != nil {
+		t.Fatalf("Failed to find rules: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("Expected alice's 2 rules on data1, got %v", found)
+	}
+	for _, rule := range found {
+		if rule.Key == "" || rule.V0 != "alice" || rule.V1 != "data1" {
+			t.Errorf("Unexpected rule %+v", rule)
+		}
+	}
+
+	// The same rules RemoveFilteredPolicy removes
+	count, err := adapter.RemoveFilteredPolicyCount(ctx, "p", "p", 1, "data1")
+	if err != nil {
+		t.Fatalf("Failed to remove rules: %v", err)
+	}
+	if count != int64(len(found)) {
+		t.Errorf("Expected FindRules to match the %d removed rules, got %d", count, len(found))
+	}
+}
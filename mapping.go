@@ -0,0 +1,351 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/arangodb/go-driver/v2/arangodb"
+)
+
+// reservedAttributes are stored by the adapter itself and can't hold rule values.
+var reservedAttributes = []string{
+	"_key", "_id", "_rev", "expiresAt", "createdAt", "updatedAt", "createdBy", "labels", "deletedAt", "deletedBy",
+}
+
+// identifier matches attribute names that can be used in AQL without quoting.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// mapping knows which document attributes hold the ptype and rule values.
+// Ptypes without a custom mapping use ptype and v0 to v5.
+type mapping struct {
+	ptypeField string
+	fields     map[string][]string           // Attributes for each position, by ptype
+	codecs     map[string]map[int]ValueCodec // Codecs for each position, by ptype
+}
+
+// newMapping validates the configured mappings.
+func newMapping(cfg *Config) (*mapping, error) {
+	m := &mapping{
+		ptypeField: cfg.PtypeField,
+		fields:     cfg.FieldMappings,
+		codecs:     cfg.ValueCodecs,
+	}
+	if m.ptypeField == "" {
+		m.ptypeField = "ptype"
+	}
+
+	reserved := make(map[string]bool)
+	for _, name := range reservedAttributes {
+		reserved[name] = true
+	}
+	if reserved[m.ptypeField] {
+		return nil, fmt.Errorf("ptype field %q is reserved", m.ptypeField)
+	}
+
+	for ptype, fields := range m.fields {
+		if len(fields) == 0 || len(fields) > len(fieldNames) {
+			return nil, fmt.Errorf("field mapping for %q must have 1 to %d fields", ptype, len(fieldNames))
+		}
+		seen := map[string]bool{m.ptypeField: true}
+		for _, name := range fields {
+			if name == "" || reserved[name] || seen[name] {
+				return nil, fmt.Errorf("field mapping for %q can't use attribute %q", ptype, name)
+			}
+			seen[name] = true
+		}
+	}
+
+	for ptype, codecs := range m.codecs {
+		for i, codec := range codecs {
+			if i < 0 || i >= len(m.fieldsFor(ptype)) || codec == nil {
+				return nil, fmt.Errorf("invalid value codec for %q at position %d", ptype, i)
+			}
+		}
+	}
+	return m, nil
+}
+
+// isDefault reports whether documents are stored as plain CasbinRule values.
+func (m *mapping) isDefault() bool {
+	return m.ptypeField == "ptype" && len(m.fields) == 0 && len(m.codecs) == 0
+}
+
+// fieldsFor returns the attributes holding the values of ptype's rules.
+func (m *mapping) fieldsFor(ptype string) []string {
+	if fields, ok := m.fields[ptype]; ok {
+		return fields
+	}
+	return fieldNames[:]
+}
+
+// attr returns the AQL expression for a document attribute.
+func attr(name string) string {
+	if identifier.MatchString(name) {
+		return "doc." + name
+	}
+	return "doc.`" + name + "`"
+}
+
+// objectKey returns name as an AQL object key.
+func objectKey(name string) string {
+	if identifier.MatchString(name) {
+		return name
+	}
+	key, _ := json.Marshal(name)
+	return string(key)
+}
+
+// ptypeExpr returns the AQL expression for a document's ptype.
+func (m *mapping) ptypeExpr() string {
+	return attr(m.ptypeField)
+}
+
+// fieldExpr returns the AQL expression for value i of a ptype's rules,
+// or an empty string if that position isn't stored.
+func (m *mapping) fieldExpr(ptype string, i int) string {
+	fields := m.fieldsFor(ptype)
+	if i >= len(fields) {
+		return ""
+	}
+	return attr(fields[i])
+}
+
+// anyFieldExpr returns the AQL expression for value i of a rule of any ptype.
+func (m *mapping) anyFieldExpr(i int) string {
+	expr := attr(fieldNames[i])
+	if len(m.fields) == 0 {
+		return expr
+	}
+
+	ptypes := make([]string, 0, len(m.fields))
+	for ptype := range m.fields {
+		ptypes = append(ptypes, ptype)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ptypes)))
+
+	// Nest ternaries so each mapped ptype picks its own attribute
+	for _, ptype := range ptypes {
+		field := "null"
+		if f := m.fieldExpr(ptype, i); f != "" {
+			field = f
+		}
+		literal, _ := json.Marshal(ptype)
+		expr = fmt.Sprintf("(%s == %s ? %s : %s)", m.ptypeExpr(), literal, field, expr)
+	}
+	return expr
+}
+
+// encode converts a rule into the document to store. Values at positions the ptype's
+// mapping doesn't cover must be empty.
+func (m *mapping) encode(rule CasbinRule) (interface{}, error) {
+	if m.isDefault() {
+		return rule, nil
+	}
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	delete(doc, "ptype")
+	for _, name := range fieldNames {
+		delete(doc, name)
+	}
+
+	doc[m.ptypeField] = rule.Ptype
+	fields := m.fieldsFor(rule.Ptype)
+	for i, value := range rule.values() {
+		if i >= len(fields) {
+			if value != "" {
+				return nil, fmt.Errorf("ptype %q has no attribute for value %d", rule.Ptype, i)
+			}
+			continue
+		}
+		if doc[fields[i]], err = m.encodeValue(rule.Ptype, i, value); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// decode converts a stored document back into a rule.
+func (m *mapping) decode(data []byte) (CasbinRule, error) {
+	var rule CasbinRule
+	if m.isDefault() {
+		err := json.Unmarshal(data, &rule)
+		return rule, err
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return CasbinRule{}, err
+	}
+
+	var ptype string
+	if raw, ok := doc[m.ptypeField]; ok {
+		if err := json.Unmarshal(raw, &ptype); err != nil {
+			return CasbinRule{}, err
+		}
+	}
+
+	values := make([]string, len(fieldNames))
+	for i, name := range m.fieldsFor(ptype) {
+		value, err := m.decodeValue(ptype, i, doc[name])
+		if err != nil {
+			return CasbinRule{}, fmt.Errorf("decoding %s: %w", name, err)
+		}
+		values[i] = value
+		delete(doc, name)
+	}
+
+	// Whatever is left is the key, expiry and metadata
+	delete(doc, m.ptypeField)
+	delete(doc, "ptype")
+	for _, name := range fieldNames {
+		delete(doc, name)
+	}
+	rest, err := json.Marshal(doc)
+	if err != nil {
+		return CasbinRule{}, err
+	}
+	if err := json.Unmarshal(rest, &rule); err != nil {
+		return CasbinRule{}, err
+	}
+
+	rule.Ptype = ptype
+	rule.setValues(values)
+	return rule, nil
+}
+
+// readRule reads the next document from cursor and decodes it.
+func (a *Adapter) readRule(ctx context.Context, cursor arangodb.Cursor) (CasbinRule, error) {
+	var raw json.RawMessage
+	if _, err := cursor.ReadDocument(ctx, &raw); err != nil {
+		return CasbinRule{}, err
+	}
+	return a.mapping.decode(raw)
+}
+
+// setValues sets V0 through V5 from values, clearing the ones values doesn't reach.
+func (r *CasbinRule) setValues(values []string) {
+	fields := []*string{&r.V0, &r.V1, &r.V2, &r.V3, &r.V4, &r.V5}
+	for i, field := range fields {
+		*field = ""
+		if i < len(values) {
+			*field = values[i]
+		}
+	}
+}
+
+// ruleConditions builds the AQL conditions that match a rule on its ptype and non-empty fields.
+func (m *mapping) ruleConditions(line CasbinRule) (string, map[string]interface{}, error) {
+	conditions := m.ptypeExpr() + " == @ptype"
+	bindVars := map[string]interface{}{
+		"ptype": line.Ptype,
+	}
+
+	// Build up the conditions dynamically based on which fields have values
+	for i, value := range line.values() {
+		if value != "" {
+			stored, err := m.encodeValue(line.Ptype, i, value)
+			if err != nil {
+				return "", nil, err
+			}
+			conditions += " && " + m.valueCondition(line.Ptype, i)
+			bindVars[fieldNames[i]] = stored
+		}
+	}
+
+	return conditions, bindVars, nil
+}
+
+// filteredConditions builds the AQL conditions for a partial filter starting at fieldIndex.
+func (m *mapping) filteredConditions(ptype string, fieldIndex int, fieldValues []string) (string, map[string]interface{}, error) {
+	conditions := m.ptypeExpr() + " == @ptype"
+	bindVars := map[string]interface{}{
+		"ptype": ptype,
+	}
+
+	// The logic here maps the field values to the right V fields based on the starting index
+	for i, name := range fieldNames {
+		if fieldIndex <= i && i < fieldIndex+len(fieldValues) {
+			stored, err := m.encodeValue(ptype, i, fieldValues[i-fieldIndex])
+			if err != nil {
+				return "", nil, err
+			}
+			conditions += " && " + m.valueCondition(ptype, i)
+			bindVars[name] = stored
+		}
+	}
+
+	return conditions, bindVars, nil
+}
+
+// valueCondition matches value i of a ptype's rules against the bind variable named after it.
+// Positions the mapping doesn't store only match empty values.
+func (m *mapping) valueCondition(ptype string, i int) string {
+	if expr := m.fieldExpr(ptype, i); expr != "" {
+		return expr + " == @" + fieldNames[i]
+	}
+	return "@" + fieldNames[i] + " == \"\""
+}
+
+// filterConditions builds the AQL conditions matching f, always leaving out soft deleted rules.
+func (m *mapping) filterConditions(f Filter) (string, map[string]interface{}, error) {
+	bindVars := map[string]interface{}{}
+	conditions := []string{notDeleted}
+	if len(f.Ptype) > 0 {
+		conditions = append(conditions, m.ptypeExpr()+" IN @ptype")
+		bindVars["ptype"] = f.Ptype
+	}
+	for i, values := range [][]string{f.V0, f.V1, f.V2, f.V3, f.V4, f.V5} {
+		if len(values) > 0 {
+			stored, err := m.encodeAny(i, values)
+			if err != nil {
+				return "", nil, err
+			}
+			conditions = append(conditions, m.anyFieldExpr(i)+" IN @"+fieldNames[i])
+			bindVars[fieldNames[i]] = stored
+		}
+	}
+	for n, c := range f.Conditions {
+		condition, err := m.condition(n, c, bindVars)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return strings.Join(conditions, " AND "), bindVars, nil
+}
+
+// updateAttributes returns the AQL object attributes that set a document's ptype and values
+// to line's, with the values taken from bind variables prefixed with "new_".
+func (m *mapping) updateAttributes(line CasbinRule) (string, map[string]interface{}, error) {
+	fields := m.fieldsFor(line.Ptype)
+	parts := []string{objectKey(m.ptypeField) + ": @new_ptype"}
+	bindVars := map[string]interface{}{
+		"new_ptype": line.Ptype,
+	}
+	for i, value := range line.values() {
+		if i >= len(fields) {
+			if value != "" {
+				return "", nil, fmt.Errorf("ptype %q has no attribute for value %d", line.Ptype, i)
+			}
+			continue
+		}
+		stored, err := m.encodeValue(line.Ptype, i, value)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, objectKey(fields[i])+": @new_"+fieldNames[i])
+		bindVars["new_"+fieldNames[i]] = stored
+	}
+	return strings.Join(parts, ", "), bindVars, nil
+}
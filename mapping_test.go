@@ -0,0 +1,158 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newTestMapping(t *testing.T, opts ...Option) *mapping {
+	m, err := newMapping(NewConfig(opts...))
+	if err != nil {
+		t.Fatalf("Failed to create mapping: %v", err)
+	}
+	return m
+}
+
+func TestNewMappingValidation(t *testing.T) {
+	invalid := map[string][]Option{
+		"reserved ptype field": {WithPtypeField("_key")},
+		"no fields":            {WithFieldMapping("p")},
+		"too many fields":      {WithFieldMapping("p", "a", "b", "c", "d", "e", "f", "g")},
+		"duplicate field":      {WithFieldMapping("p", "subject", "subject")},
+		"reserved field":       {WithFieldMapping("p", "subject", "createdAt")},
+		"ptype field":          {WithFieldMapping("p", "ptype", "resource")},
+	}
+	for name, opts := range invalid {
+		if _, err := newMapping(NewConfig(opts...)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDefaultMapping(t *testing.T) {
+	m := newTestMapping(t)
+
+	rule := CasbinRule{Ptype: "p", V0: "alice", V1: "data1", V2: "read"}
+	doc, err := m.encode(rule)
+	if err != nil {
+		t.Fatalf("Failed to encode rule: %v", err)
+	}
+	if _, ok := doc.(CasbinRule); !ok {
+		t.Errorf("Default mapping should store rules as they are, got %T", doc)
+	}
+
+	conditions, _, _ := m.ruleConditions(rule)
+	if conditions != "doc.ptype == @ptype && doc.v0 == @v0 && doc.v1 == @v1 && doc.v2 == @v2" {
+		t.Errorf("Unexpected conditions: %s", conditions)
+	}
+	if expr := m.anyFieldExpr(1); expr != "doc.v1" {
+		t.Errorf("Unexpected field expression: %s", expr)
+	}
+}
+
+func TestCustomMapping(t *testing.T) {
+	m := newTestMapping(t,
+		WithPtypeField("type"),
+		WithFieldMapping("p", "subject", "resource", "action", "tenant"),
+	)
+
+	rule := CasbinRule{Key: "1", Ptype: "p", V0: "alice", V1: "data1", V2: "read", V3: "acme", CreatedBy: "admin"}
+	doc, err := m.encode(rule)
+	if err != nil {
+		t.Fatalf("Failed to encode rule: %v", err)
+	}
+	data, _ := json.Marshal(doc)
+
+	var stored map[string]interface{}
+	_ = json.Unmarshal(data, &stored)
+	if stored["type"] != "p" || stored["subject"] != "alice" || stored["tenant"] != "acme" || stored["createdBy"] != "admin" {
+		t.Errorf("Rule wasn't mapped to the right attributes: %v", stored)
+	}
+	if _, ok := stored["v0"]; ok {
+		t.Error("Mapped rule shouldn't have v0")
+	}
+
+	decoded, err := m.decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode rule: %v", err)
+	}
+	if decoded.Ptype != "p" || decoded.V0 != "alice" || decoded.V3 != "acme" || decoded.Key != "1" || decoded.CreatedBy != "admin" {
+		t.Errorf("Rule didn't round-trip: %+v", decoded)
+	}
+
+	// Unmapped ptypes keep the defaults
+	decoded, _ = m.decode([]byte(`{"type": "g", "v0": "alice", "v1": "admin"}`))
+	if decoded.Ptype != "g" || decoded.V0 != "alice" || decoded.V1 != "admin" {
+		t.Errorf("Unmapped ptype should use v0 to v5: %+v", decoded)
+	}
+
+	if _, err := m.encode(CasbinRule{Ptype: "p", V4: "extra"}); err == nil {
+		t.Error("Expected an error for a value without an attribute")
+	}
+
+	conditions, _, _ := m.filteredConditions("p", 1, []string{"data1", "read"})
+	if conditions != "doc.type == @ptype && doc.resource == @v1 && doc.action == @v2" {
+		t.Errorf("Unexpected conditions: %s", conditions)
+	}
+
+	conditions, _, _ = m.ruleConditions(CasbinRule{Ptype: "p", V0: "alice", V5: "x"})
+	if !strings.Contains(conditions, `@v5 == ""`) {
+		t.Errorf("Unmapped position should only match empty values: %s", conditions)
+	}
+
+	if expr := m.anyFieldExpr(0); expr != `(doc.type == "p" ? doc.subject : doc.v0)` {
+		t.Errorf("Unexpected field expression: %s", expr)
+	}
+
+	updates, bindVars, err := m.updateAttributes(CasbinRule{Ptype: "p", V0: "bob"})
+	if err != nil {
+		t.Fatalf("Failed to build update: %v", err)
+	}
+	if updates != "type: @new_ptype, subject: @new_v0, resource: @new_v1, action: @new_v2, tenant: @new_v3" || bindVars["new_v0"] != "bob" {
+		t.Errorf("Unexpected update: %s %v", updates, bindVars)
+	}
+}
+
+func TestFieldMappingIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t, WithFieldMapping("p", "subject", "resource", "action"))
+	defer teardownTestAdapter(t, adapter)
+
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = adapter.AddPolicy("p", "p", []string{"bob", "data2", "write"})
+	_ = adapter.AddPolicy("g", "g", []string{"alice", "admin"})
+
+	m := newTestModel()
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "data1", "read"}); !ok {
+		t.Error("Mapped rule should load")
+	}
+	if ok, _ := m.HasPolicy("g", "g", []string{"alice", "admin"}); !ok {
+		t.Error("Unmapped rule should load")
+	}
+
+	m = newTestModel()
+	if err := adapter.LoadFilteredPolicy(m, Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatalf("Failed to load filtered policy: %v", err)
+	}
+	policies, _ := m.GetPolicy("p", "p")
+	groupings, _ := m.GetPolicy("g", "g")
+	if len(policies) != 1 || len(groupings) != 1 {
+		t.Errorf("Filter should match both ptypes, got %v and %v", policies, groupings)
+	}
+
+	if err := adapter.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"bob", "data2", "read"}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	if err := adapter.RemoveFilteredPolicy("p", "p", 1, "data2"); err != nil {
+		t.Fatalf("Failed to remove policy: %v", err)
+	}
+
+	rules, _ := adapter.GetRules(context.Background(), Filter{Ptype: []string{"p"}})
+	if len(rules) != 1 || rules[0].V0 != "alice" {
+		t.Errorf("Expected only alice's rule left, got %v", rules)
+	}
+}
@@ -0,0 +1,129 @@
+package arangoadapter
+
+import (
+	"context"
+	"time"
+)
+
+// labelsKey is the context key holding the labels for new rules.
+type labelsKey struct{}
+
+// WithLabels returns a context that attaches labels to rules added with it,
+// e.g. a ticket reference.
+func WithLabels(ctx context.Context, labels map[string]string) context.Context {
+	return context.WithValue(ctx, labelsKey{}, labels)
+}
+
+// LabelsFromContext returns the labels set with WithLabels, or nil.
+func LabelsFromContext(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(labelsKey{}).(map[string]string)
+	return labels
+}
+
+// stampCreated fills in the creation metadata of lines that don't have any yet.
+func stampCreated(ctx context.Context, lines []CasbinRule) {
+	now := formatTime(time.Now())
+	actor := ActorFromContext(ctx)
+	labels := LabelsFromContext(ctx)
+	for i := range lines {
+		if lines[i].CreatedAt != "" {
+			continue
+		}
+		lines[i].CreatedAt = now
+		lines[i].UpdatedAt = now
+		lines[i].CreatedBy = actor
+		lines[i].Labels = labels
+	}
+}
+
+// inherit copies everything but the rule values over from the stored version of the rule.
+func (r *CasbinRule) inherit(stored CasbinRule) {
+	r.ExpiresAt = stored.ExpiresAt
+	r.CreatedAt = stored.CreatedAt
+	r.UpdatedAt = stored.UpdatedAt
+	r.CreatedBy = stored.CreatedBy
+	r.Labels = stored.Labels
+}
+
+// keepStored lets lines inherit the expiry time, metadata and document key of matching stored
+// rules. Keeping the key lets the rule's revision history carry on.
+func (a *Adapter) keepStored(ctx context.Context, lines []CasbinRule) error {
+	stored := make(map[string]CasbinRule)
+	err := a.eachRule(ctx, a.routing.names(), notDeleted, map[string]interface{}{}, func(rule CasbinRule) error {
+		stored[rule.identity()] = rule
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]bool)
+	for i := range lines {
+		if rule, ok := stored[lines[i].identity()]; ok {
+			lines[i].inherit(rule)
+
+			// Duplicates in lines get keys of their own
+			lines[i].Key = ""
+			if !used[rule.Key] {
+				lines[i].Key = rule.Key
+				used[rule.Key] = true
+			}
+		}
+	}
+	return nil
+}
+
+// GetRules returns the stored rules matching filter along with their document keys and
+// metadata. An empty filter returns every rule.
+func (a *Adapter) GetRules(ctx context.Context, filter Filter) (rules []CasbinRule, err error) {
+	ctx, op := a.startOperation(ctx, "GetRules")
+	defer func() {
+		op.setRuleCount(len(rules))
+		op.end(err)
+	}()
+
+	conditions, bindVars, err := a.mapping.filterConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+	err = a.eachRule(ctx, a.routing.namesFor(filter.Ptype), conditions, bindVars, func(rule CasbinRule) error {
+		rules = append(rules, rule)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// SetRuleLabels replaces the labels of the rule stored under key.
+// It returns ErrRuleNotFound if there's no such rule.
+func (a *Adapter) SetRuleLabels(ctx context.Context, key string, labels map[string]string) (err error) {
+	ctx, op := a.startOperation(ctx, "SetRuleLabels")
+	defer func() { op.end(err) }()
+
+	// Replace rather than merge the labels object, so removed labels go away
+	query := "FOR doc IN @@collection FILTER doc._key == @key && " + notDeleted +
+		" UPDATE doc WITH { labels: @labels, updatedAt: @now } IN @@collection OPTIONS { mergeObjects: false }"
+	bindVars := map[string]interface{}{
+		"key":    key,
+		"labels": labels,
+		"now":    formatTime(time.Now()),
+	}
+
+	// The key doesn't say which collection the rule is in, so try them all
+	var updated int64
+	for _, name := range a.routing.names() {
+		bindVars["@collection"] = name
+		_, count, err := a.writeQuery(ctx, query, bindVars, false)
+		if err != nil {
+			return err
+		}
+		updated += count
+	}
+
+	if updated == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
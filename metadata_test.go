@@ -0,0 +1,91 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStampCreated(t *testing.T) {
+	ctx := WithActor(context.Background(), "admin")
+	ctx = WithLabels(ctx, map[string]string{"ticket": "SEC-42"})
+
+	lines := []CasbinRule{
+		{Ptype: "p", V0: "alice"},
+		{Ptype: "p", V0: "bob", CreatedAt: "2024-01-01T00:00:00.000Z", CreatedBy: "someone"},
+	}
+	stampCreated(ctx, lines)
+
+	if lines[0].CreatedAt == "" || lines[0].UpdatedAt != lines[0].CreatedAt {
+		t.Errorf("New rule should get creation timestamps, got %+v", lines[0])
+	}
+	if lines[0].CreatedBy != "admin" || lines[0].Labels["ticket"] != "SEC-42" {
+		t.Errorf("New rule should get the actor and labels from the context, got %+v", lines[0])
+	}
+	if lines[1].CreatedBy != "someone" || lines[1].CreatedAt != "2024-01-01T00:00:00.000Z" {
+		t.Errorf("Existing metadata shouldn't be overwritten, got %+v", lines[1])
+	}
+}
+
+func TestLabelsFromContext(t *testing.T) {
+	if labels := LabelsFromContext(context.Background()); labels != nil {
+		t.Errorf("Expected no labels, got %v", labels)
+	}
+}
+
+func TestRuleMetadata(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := WithLabels(WithActor(context.Background(), "admin"), map[string]string{"ticket": "SEC-42"})
+	start := formatTime(time.Now().Add(-time.Second))
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	rules, err := adapter.GetRules(ctx, Filter{V0: []string{"alice"}})
+	if err != nil {
+		t.Fatalf("Failed to get rules: %v", err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("Expected 1 rule, got %d", len(rules))
+	}
+	rule := rules[0]
+	if rule.Key == "" || rule.CreatedBy != "admin" || rule.CreatedAt < start || rule.Labels["ticket"] != "SEC-42" {
+		t.Errorf("Rule should carry its key and metadata, got %+v", rule)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if err := adapter.UpdatePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	rules, _ = adapter.GetRules(ctx, Filter{V0: []string{"alice"}})
+	if rules[0].UpdatedAt <= rules[0].CreatedAt || rules[0].CreatedBy != "admin" {
+		t.Errorf("Update should bump updatedAt and keep the rest, got %+v", rules[0])
+	}
+
+	if err := adapter.SetRuleLabels(ctx, rule.Key, map[string]string{"owner": "team-a"}); err != nil {
+		t.Fatalf("Failed to set labels: %v", err)
+	}
+	rules, _ = adapter.GetRules(ctx, Filter{V0: []string{"alice"}})
+	if len(rules[0].Labels) != 1 || rules[0].Labels["owner"] != "team-a" {
+		t.Errorf("Labels should be replaced, got %v", rules[0].Labels)
+	}
+	if err := adapter.SetRuleLabels(ctx, "missing", nil); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound for a missing rule, got %v", err)
+	}
+
+	// Saving the model keeps the metadata of rules that were already there
+	m := newTestModel()
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if err := adapter.SavePolicyCtx(context.Background(), m); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+	rules, _ = adapter.GetRules(ctx, Filter{})
+	if len(rules) != 1 || rules[0].CreatedAt != rule.CreatedAt || rules[0].Labels["owner"] != "team-a" {
+		t.Errorf("SavePolicy should keep metadata, got %+v", rules)
+	}
+}
@@ -0,0 +1,95 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb/shared"
+)
+
+// MetricsRecorder receives measurements about adapter operations.
+// Implementations must be safe for concurrent use. See the prommetrics
+// package for a Prometheus implementation.
+type MetricsRecorder interface {
+	// ObserveOperation is called when an adapter operation finishes. Operations that other
+	// operations call internally aren't reported on their own.
+	ObserveOperation(operation string, duration time.Duration, err error)
+
+	// ObserveRulesLoaded is called after LoadPolicy with the number of rules loaded.
+	ObserveRulesLoaded(count int)
+
+	// ObserveTransaction is called when a transaction is committed or aborted.
+	ObserveTransaction(committed bool)
+
+	// SetCollectionSize reports the number of documents in the policy collections.
+	SetCollectionSize(count int64)
+}
+
+// nopMetrics is used when no MetricsRecorder is configured.
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveOperation(string, time.Duration, error) {}
+func (nopMetrics) ObserveRulesLoaded(int)                        {}
+func (nopMetrics) ObserveTransaction(bool)                       {}
+func (nopMetrics) SetCollectionSize(int64)                       {}
+
+// ErrorCode returns a short, low-cardinality label for err, suitable for metrics.
+// ArangoDB errors map to their errorNum (e.g. "1200"), falling back to the HTTP status code.
+func ErrorCode(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrRuleNotFound):
+		return "rule_not_found"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	}
+
+	if ok, arangoErr := shared.IsArangoError(err); ok {
+		if arangoErr.ErrorNum != 0 {
+			return strconv.Itoa(arangoErr.ErrorNum)
+		}
+		return strconv.Itoa(arangoErr.Code)
+	}
+	return "unknown"
+}
+
+// defaultCollectionSizeInterval is how often writes refresh the collection size at most.
+const defaultCollectionSizeInterval = 30 * time.Second
+
+// refreshCollectionSize reports the current document count of the policy collections, unless
+// it was reported less than sizeInterval ago. It's skipped without a metrics recorder and
+// inside transactions, where the count isn't visible to anyone else yet.
+func (a *Adapter) refreshCollectionSize(ctx context.Context) {
+	if _, ok := a.metrics.(nopMetrics); ok || a.transaction != nil || a.sizeInterval < 0 {
+		return
+	}
+
+	// Only one of the writes finishing at the same time does the counting
+	now := time.Now().UnixNano()
+	last := a.sizeRefreshed.Load()
+	if now-last < int64(a.sizeInterval) || !a.sizeRefreshed.CompareAndSwap(last, now) {
+		return
+	}
+	_ = a.ReportCollectionSize(ctx)
+}
+
+// ReportCollectionSize counts the documents in the policy collections and reports the count
+// to the metrics recorder right away, e.g. from a metrics scrape handler.
+func (a *Adapter) ReportCollectionSize(ctx context.Context) error {
+	var total int64
+	for _, col := range a.collections {
+		count, err := col.Count(ctx)
+		if err != nil {
+			return err
+		}
+		total += count
+	}
+	a.sizeRefreshed.Store(time.Now().UnixNano())
+	a.metrics.SetCollectionSize(total)
+	return nil
+}
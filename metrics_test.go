@@ -0,0 +1,90 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb/shared"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{ErrRuleNotFound, "rule_not_found"},
+		{fmt.Errorf("wrapped: %w", context.Canceled), "canceled"},
+		{context.DeadlineExceeded, "deadline_exceeded"},
+		{shared.ArangoError{HasError: true, Code: http.StatusConflict, ErrorNum: shared.ErrArangoConflict}, "1200"},
+		{shared.ArangoError{HasError: true, Code: http.StatusServiceUnavailable}, "503"},
+		{errors.New("boom"), "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorCode(tt.err); got != tt.want {
+			t.Errorf("ErrorCode(%v): expected %q, got %q", tt.err, tt.want, got)
+		}
+	}
+}
+
+// operationRecorder collects the names of the operations it observes.
+type operationRecorder struct {
+	nopMetrics
+	names []string
+}
+
+func (r *operationRecorder) ObserveOperation(name string, _ time.Duration, _ error) {
+	r.names = append(r.names, name)
+}
+
+func TestNestedOperationsObservedOnce(t *testing.T) {
+	recorder := &operationRecorder{}
+	a := &Adapter{tracer: newTracer(nil), metrics: recorder, logger: slog.New(discardHandler{})}
+
+	ctx, outer := a.startOperation(context.Background(), "UpdatePolicies")
+	_, inner := a.startOperation(ctx, "AddPolicy")
+	inner.end(nil)
+	outer.end(nil)
+	if len(recorder.names) != 1 || recorder.names[0] != "UpdatePolicies" {
+		t.Errorf("Expected only the outer operation observed, got %v", recorder.names)
+	}
+}
+
+// sizeRecorder counts the collection sizes it's given.
+type sizeRecorder struct {
+	nopMetrics
+	reports int
+}
+
+func (r *sizeRecorder) SetCollectionSize(int64) {
+	r.reports++
+}
+
+func TestRefreshCollectionSize(t *testing.T) {
+	recorder := &sizeRecorder{}
+	a := &Adapter{metrics: recorder, sizeInterval: time.Hour, sizeRefreshed: &atomic.Int64{}}
+	ctx := context.Background()
+
+	a.refreshCollectionSize(ctx)
+	a.refreshCollectionSize(ctx)
+	if recorder.reports != 1 {
+		t.Errorf("Expected one refresh per interval, got %d", recorder.reports)
+	}
+	if err := a.ReportCollectionSize(ctx); err != nil || recorder.reports != 2 {
+		t.Errorf("Expected reporting on demand to skip the interval, got %d, %v", recorder.reports, err)
+	}
+
+	a.sizeInterval = -1
+	a.sizeRefreshed.Store(0)
+	a.refreshCollectionSize(ctx)
+	if recorder.reports != 2 {
+		t.Error("A negative interval shouldn't refresh after writes")
+	}
+}
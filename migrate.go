@@ -0,0 +1,170 @@
+package arangoadapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// ErrMigrationMismatch is returned by MigrateFrom when the stored rules don't match the
+// source's after migrating. The report says how they differ.
+var ErrMigrationMismatch = errors.New("migrated rules don't match the source")
+
+// MigrateOptions controls MigrateFrom.
+type MigrateOptions struct {
+	BatchSize int  // Rules written per batch (default: 1000)
+	DryRun    bool // Only report what would be migrated
+	Resume    bool // Keep the stored rules and only write the missing ones, e.g. after an interrupted run
+}
+
+// MigrationReport describes a migration.
+type MigrationReport struct {
+	SourceRules    int    // Rules read from the source
+	Migrated       int    // Rules written, or that would be written in a dry run
+	Skipped        int    // Rules that were stored already when resuming
+	StoredRules    int    // Rules stored afterwards
+	SourceChecksum string // SHA-256 over the source's rules, independent of their order
+	StoredChecksum string // The same over the stored rules; empty in a dry run
+}
+
+// MigrateFrom copies every policy and grouping rule from another Casbin adapter, such as
+// the gorm or file adapter, into this one. The source is read through its LoadPolicy into
+// m, which has to define all of its ptypes, and its rules are written in batches.
+//
+// A normal run replaces the stored rules first. If it's interrupted, run it again with
+// Resume set to pick up where it left off: rules already stored are skipped. Afterwards the
+// stored rules are counted and checksummed, and ErrMigrationMismatch is returned along with
+// the report if they don't match the source's.
+func (a *Adapter) MigrateFrom(ctx context.Context, source persist.Adapter, m model.Model, opts MigrateOptions) (report MigrationReport, err error) {
+	ctx, op := a.startWrite(ctx, "MigrateFrom")
+	defer func() {
+		op.setRuleCount(report.Migrated)
+		op.end(err)
+	}()
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+
+	if err := source.LoadPolicy(m); err != nil {
+		return report, fmt.Errorf("loading source policy: %w", err)
+	}
+	lines := a.modelRules(m)
+	report.SourceRules = len(lines)
+	report.SourceChecksum = rulesChecksum(lines)
+
+	// Write in a fixed order, so runs over the same source make the same batches
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].identity() < lines[j].identity()
+	})
+
+	if opts.Resume {
+		stored := make(map[string]bool)
+		err := a.eachRule(ctx, a.routing.names(), notDeleted, map[string]interface{}{}, func(rule CasbinRule) error {
+			stored[rule.identity()] = true
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+
+		missing := lines[:0]
+		for _, line := range lines {
+			if stored[line.identity()] {
+				report.Skipped++
+				continue
+			}
+			missing = append(missing, line)
+		}
+		lines = missing
+	}
+
+	if opts.DryRun {
+		report.Migrated = len(lines)
+		return report, nil
+	}
+
+	if !opts.Resume {
+		err := a.recorded(ctx, func(tx *Adapter) error {
+			for _, name := range tx.routing.names() {
+				if err := tx.clearCollection(ctx, name, []string{}); err != nil {
+					return err
+				}
+			}
+			return tx.record(ctx, change{operation: AuditMigratePolicy, replaced: true})
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	// Each batch is written on its own, so an interrupted run keeps what it wrote
+	for start := 0; start < len(lines); start += opts.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		batch := lines[start:min(start+opts.BatchSize, len(lines))]
+		stampCreated(ctx, batch)
+		err := a.recorded(ctx, func(tx *Adapter) error {
+			if err := tx.bulkInsert(ctx, batch); err != nil {
+				return err
+			}
+			return tx.record(ctx, change{operation: AuditMigratePolicy, added: batch})
+		})
+		if err != nil {
+			return report, err
+		}
+
+		report.Migrated += len(batch)
+		a.logger.InfoContext(ctx, "migrated rules",
+			slog.Int("migrated", report.Migrated+report.Skipped),
+			slog.Int("total", report.SourceRules),
+		)
+	}
+
+	return report, a.verifyMigration(ctx, &report)
+}
+
+// verifyMigration counts and checksums the stored rules and compares them with the source's.
+func (a *Adapter) verifyMigration(ctx context.Context, report *MigrationReport) error {
+	var stored []CasbinRule
+	err := a.eachRule(ctx, a.routing.names(), notDeleted, map[string]interface{}{}, func(rule CasbinRule) error {
+		stored = append(stored, rule)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	report.StoredRules = len(stored)
+	report.StoredChecksum = rulesChecksum(stored)
+	if report.StoredRules != report.SourceRules || report.StoredChecksum != report.SourceChecksum {
+		return fmt.Errorf("%w: %d rules in the source, %d stored", ErrMigrationMismatch, report.SourceRules, report.StoredRules)
+	}
+	return nil
+}
+
+// rulesChecksum returns a SHA-256 over the ptypes and values of rules, in sorted order
+// so it doesn't depend on the order they were read in.
+func rulesChecksum(rules []CasbinRule) string {
+	identities := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		identities = append(identities, rule.identity())
+	}
+	sort.Strings(identities)
+
+	h := sha256.New()
+	for _, identity := range identities {
+		h.Write([]byte(identity))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
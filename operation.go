@@ -0,0 +1,72 @@
+package arangoadapter
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// operationKey marks the context of an operation that is already running,
+// so nested calls (e.g. RemovePolicies calling RemovePolicy) can tell.
+type operationKey struct{}
+
+// operation tracks a single adapter call from start to finish.
+type operation struct {
+	adapter *Adapter
+	ctx     context.Context
+	name    string
+	start   time.Time
+	span    trace.Span
+	nested  bool
+	write   bool // Whether the call changes the number of stored rules
+}
+
+// startOperation starts tracking the named adapter operation as a child of ctx.
+// Callers must end the returned operation with the call's error.
+func (a *Adapter) startOperation(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *operation) {
+	_, nested := ctx.Value(operationKey{}).(*operation)
+
+	ctx, span := a.startSpan(ctx, name, attrs...)
+	op := &operation{
+		adapter: a,
+		name:    name,
+		start:   time.Now(),
+		span:    span,
+		nested:  nested,
+	}
+	op.ctx = context.WithValue(ctx, operationKey{}, op)
+	return op.ctx, op
+}
+
+// startWrite is like startOperation for calls that add or remove rules.
+func (a *Adapter) startWrite(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *operation) {
+	ctx, op := a.startOperation(ctx, name, attrs...)
+	op.write = true
+	return ctx, op
+}
+
+// setRuleCount records how many rules the operation touched.
+func (op *operation) setRuleCount(n int) {
+	op.span.SetAttributes(attrRuleCount.Int(n))
+}
+
+// end records err, if any, and finishes the operation.
+func (op *operation) end(err error) {
+	duration := time.Since(op.start)
+	// Calls made by another adapter method are already part of its duration and error
+	if !op.nested {
+		op.adapter.metrics.ObserveOperation(op.name, duration, err)
+	}
+	op.adapter.logger.DebugContext(op.ctx, "operation finished",
+		slog.String("operation", op.name),
+		slog.Duration("duration", duration),
+		slog.Any("error", err),
+	)
+	if op.write && !op.nested && err == nil {
+		op.adapter.refreshCollectionSize(op.ctx)
+	}
+	endSpan(op.span, err)
+}
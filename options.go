@@ -3,10 +3,13 @@ package arangoadapter
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/arangodb/go-driver/v2/arangodb"
 	"github.com/arangodb/go-driver/v2/connection"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/http2"
 )
 
@@ -20,6 +23,48 @@ type Config struct {
 	TLSEnabled     bool        // Whether to use TLS
 	CACertPath     string      // Path to CA certificate file (for TLS)
 	TLSConfig      *tls.Config // Custom TLS configuration (optional)
+	ErrOnNoMatch   bool        // Return ErrRuleNotFound when a remove deletes nothing
+	SoftDelete     bool        // Flag removed rules instead of deleting them
+
+	LenientLoad     bool                        // Skip rules that can't be loaded instead of failing the load
+	LenientLoadHook func(skipped []SkippedRule) // Called with the rules a lenient load skipped (optional)
+
+	SectionCollections map[string]string // Collections for the rules of a section ("p" or "g"), by section
+	PtypeCollections   map[string]string // Collections for the rules of a ptype, by ptype, taking precedence over sections
+
+	PtypeField    string                        // Attribute holding the ptype (default: "ptype")
+	FieldMappings map[string][]string           // Attributes holding the rule values, by ptype (default: v0 to v5)
+	ValueCodecs   map[string]map[int]ValueCodec // Codecs for rule values, by ptype and position
+	Retry         *RetryPolicy                  // Retry policy for transient errors (optional)
+
+	SchemaValidation arangodb.CollectionSchemaLevel // Validate rule documents at this level (optional)
+	SortIndexes      []string                       // Rule fields (e.g. "v0") to index for sorting in ListRules
+
+	TracerProvider trace.TracerProvider // OpenTelemetry tracer provider (optional)
+	Metrics        MetricsRecorder      // Metrics hook (optional)
+
+	// Minimum time between collection size refreshes after writes (default: 30s, negative: never)
+	CollectionSizeInterval time.Duration
+
+	Logger       *slog.Logger // Structured logger (optional, silent by default)
+	LogQueries   bool         // Log AQL queries and bind vars at debug level
+	RedactFields []string     // Rule fields (e.g. "v1") whose values are redacted from query logs
+
+	AuditEnabled        bool   // Record every policy change in an audit collection
+	AuditCollectionName string // Audit collection name (default: "<collection>_audit")
+
+	VersioningEnabled     bool   // Keep a revision history of every rule
+	HistoryCollectionName string // History collection name (default: "<collection>_history")
+
+	MetaCollectionName string            // Metadata collection name (default: "<collection>_meta")
+	NoMetaCollection   bool              // Don't track the schema version, see WithoutMetaCollection
+	SchemaMigrations   []SchemaMigration // Migrations to schema versions above SchemaVersion
+	StrictSchema       bool              // Refuse to start on an outdated schema instead of migrating it
+	ReadOnly           bool              // Open existing collections without creating, indexing or migrating anything
+
+	Expiry         bool                       // Add the TTL index that deletes expired rules up front
+	ExpiryInterval time.Duration              // How often the expiry watcher checks for expired rules
+	ExpiryHook     func(expired []CasbinRule) // Called with rules the expiry watcher removed (optional)
 }
 
 // Option is a functional option for configuring the adapter.
@@ -54,6 +99,28 @@ func WithCollection(name string) Option {
 	}
 }
 
+// WithSectionCollection stores the rules of a section, e.g. every "g", "g2" and so on
+// grouping rule, in their own collection instead of the main one.
+func WithSectionCollection(sec string, name string) Option {
+	return func(c *Config) {
+		if c.SectionCollections == nil {
+			c.SectionCollections = make(map[string]string)
+		}
+		c.SectionCollections[sec] = name
+	}
+}
+
+// WithPtypeCollection stores the rules of a single ptype in their own collection.
+// It takes precedence over WithSectionCollection.
+func WithPtypeCollection(ptype string, name string) Option {
+	return func(c *Config) {
+		if c.PtypeCollections == nil {
+			c.PtypeCollections = make(map[string]string)
+		}
+		c.PtypeCollections[ptype] = name
+	}
+}
+
 // WithTLS enables TLS and optionally sets a CA certificate path.
 func WithTLS(caCertPath string) Option {
 	return func(c *Config) {
@@ -70,6 +137,221 @@ func WithTLSConfig(tlsConfig *tls.Config) Option {
 	}
 }
 
+// WithErrOnNoMatch makes RemovePolicy and RemoveFilteredPolicy return ErrRuleNotFound
+// when no document matched. By default removing a missing rule succeeds silently.
+func WithErrOnNoMatch() Option {
+	return func(c *Config) {
+		c.ErrOnNoMatch = true
+	}
+}
+
+// WithRetry retries write conflicts and transient errors with exponential backoff.
+// Use DefaultRetryPolicy() as a starting point.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Config) {
+		c.Retry = &policy
+	}
+}
+
+// WithTracerProvider enables OpenTelemetry spans for every adapter operation.
+// Spans are children of the context passed to the *Ctx methods.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *Config) {
+		c.TracerProvider = tp
+	}
+}
+
+// WithMetrics reports operation latency, errors, loaded rules, transaction
+// outcomes and collection size to m. See the prommetrics package for Prometheus.
+func WithMetrics(m MetricsRecorder) Option {
+	return func(c *Config) {
+		c.Metrics = m
+	}
+}
+
+// WithCollectionSizeInterval sets how often, at most, writes refresh the collection size
+// reported to the metrics recorder, as each refresh counts the documents. A negative
+// interval never refreshes it after writes; call ReportCollectionSize instead.
+func WithCollectionSizeInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.CollectionSizeInterval = interval
+	}
+}
+
+// WithLogger logs connection setup, auto-creation, retries and transaction outcomes to logger.
+// Individual operations are logged at debug level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithQueryLogging also logs every AQL query with its bind vars at debug level.
+// Values of the listed rule fields (e.g. "v0", "v1") are replaced with [REDACTED].
+func WithQueryLogging(redactFields ...string) Option {
+	return func(c *Config) {
+		c.LogQueries = true
+		c.RedactFields = redactFields
+	}
+}
+
+// WithAudit records every policy change in an audit collection, written in the same
+// transaction as the change itself. An empty name uses the policy collection name
+// with an "_audit" suffix. Use WithActor on the context to record who made the change.
+func WithAudit(collectionName string) Option {
+	return func(c *Config) {
+		c.AuditEnabled = true
+		c.AuditCollectionName = collectionName
+	}
+}
+
+// WithVersioning keeps a revision history of every rule in a separate collection, written
+// in the same transaction as the change itself. It enables LoadPolicyAt and RestorePolicyAt.
+// An empty name uses the policy collection name with a "_history" suffix.
+func WithVersioning(collectionName string) Option {
+	return func(c *Config) {
+		c.VersioningEnabled = true
+		c.HistoryCollectionName = collectionName
+	}
+}
+
+// WithExpiry adds the TTL index that lets ArangoDB delete expired rules to every rule
+// collection when the adapter starts. Without it, the index is only added to a collection
+// on the first AddPolicyWithExpiry. ArangoDB allows one TTL index per collection, so leave
+// this off for collections that already have one of their own.
+func WithExpiry() Option {
+	return func(c *Config) {
+		c.Expiry = true
+	}
+}
+
+// WithExpiryHook starts a watcher that removes expired rules every interval and calls hook
+// with them, e.g. to tell enforcers to reload through a casbin Watcher. Removing them this
+// way records them in the audit log and history. No TTL index is added, since ArangoDB could
+// delete rules before the watcher sees them. Close the adapter to stop the watcher.
+func WithExpiryHook(interval time.Duration, hook func(expired []CasbinRule)) Option {
+	return func(c *Config) {
+		c.ExpiryInterval = interval
+		c.ExpiryHook = hook
+	}
+}
+
+// WithSoftDelete makes the remove methods flag rules with deletedAt and deletedBy instead of
+// deleting them. Flagged rules are ignored everywhere else but can be brought back with
+// RestoreRemovedPolicy until PurgeRemovedBefore deletes them for good.
+func WithSoftDelete() Option {
+	return func(c *Config) {
+		c.SoftDelete = true
+	}
+}
+
+// WithLenientLoad makes LoadPolicy and LoadFilteredPolicy skip rules that can't be loaded,
+// such as ones with a ptype the model doesn't define, instead of failing halfway. Skipped rules
+// are logged and passed to hook, which may be nil. Use LoadPolicyLenient to get them directly.
+func WithLenientLoad(hook func(skipped []SkippedRule)) Option {
+	return func(c *Config) {
+		c.LenientLoad = true
+		c.LenientLoadHook = hook
+	}
+}
+
+// WithFieldMapping stores the values of ptype's rules in the given document attributes,
+// in order, instead of v0 to v5. Use it to run the adapter on an existing collection, e.g.
+// WithFieldMapping("p", "subject", "resource", "action", "tenant").
+func WithFieldMapping(ptype string, fields ...string) Option {
+	return func(c *Config) {
+		if c.FieldMappings == nil {
+			c.FieldMappings = make(map[string][]string)
+		}
+		c.FieldMappings[ptype] = fields
+	}
+}
+
+// WithValueCodec converts value field (0 for v0) of ptype's rules with codec when storing
+// and loading them, e.g. WithValueCodec("p", 3, JSONCodec{}) to store ABAC attributes natively.
+func WithValueCodec(ptype string, field int, codec ValueCodec) Option {
+	return func(c *Config) {
+		if c.ValueCodecs == nil {
+			c.ValueCodecs = make(map[string]map[int]ValueCodec)
+		}
+		if c.ValueCodecs[ptype] == nil {
+			c.ValueCodecs[ptype] = make(map[int]ValueCodec)
+		}
+		c.ValueCodecs[ptype][field] = codec
+	}
+}
+
+// WithSchemaValidation installs a JSON schema on the rule collections so ArangoDB rejects
+// malformed documents, such as a missing ptype or a numeric v0, when they're written instead
+// of when they're loaded. The level says which writes are checked, e.g.
+// arangodb.CollectionSchemaLevelStrict for all of them or CollectionSchemaLevelModerate to
+// let existing invalid documents be updated.
+func WithSchemaValidation(level arangodb.CollectionSchemaLevel) Option {
+	return func(c *Config) {
+		c.SchemaValidation = level
+	}
+}
+
+// WithSortIndexes adds an index on each of the given rule fields ("v0" to "v5") so ListRules
+// can sort by them without reading every matching rule. Each index slows down writes a little,
+// so only add the ones an admin screen sorts by.
+func WithSortIndexes(fields ...string) Option {
+	return func(c *Config) {
+		c.SortIndexes = append(c.SortIndexes, fields...)
+	}
+}
+
+// WithPtypeField stores the ptype in the given document attribute instead of "ptype".
+func WithPtypeField(name string) Option {
+	return func(c *Config) {
+		c.PtypeField = name
+	}
+}
+
+// WithSchemaMigration registers a migration for your own changes to the stored rules,
+// run by NewAdapter when the stored schema version is below m.Version.
+func WithSchemaMigration(m SchemaMigration) Option {
+	return func(c *Config) {
+		c.SchemaMigrations = append(c.SchemaMigrations, m)
+	}
+}
+
+// WithStrictSchema makes NewAdapter fail with ErrSchemaOutdated instead of migrating
+// rules stored with an older schema version, e.g. to run migrations as a separate
+// deployment step.
+func WithStrictSchema() Option {
+	return func(c *Config) {
+		c.StrictSchema = true
+	}
+}
+
+// WithMetaCollection keeps the schema version and migration lock in the named collection
+// instead of the policy collection name with a "_meta" suffix.
+func WithMetaCollection(name string) Option {
+	return func(c *Config) {
+		c.MetaCollectionName = name
+	}
+}
+
+// WithoutMetaCollection doesn't track the schema version at all, for deployments that
+// can't have the extra collection. The stored rules are then assumed to be up to date,
+// so no migrations run and registering any is an error.
+func WithoutMetaCollection() Option {
+	return func(c *Config) {
+		c.NoMetaCollection = true
+	}
+}
+
+// WithReadOnly opens the database and collections as they are, for tools that only read
+// rules: nothing is created, no indexes are added and no migrations run, so a missing
+// database or collection is an error. An outdated schema is only logged. Write methods
+// aren't blocked, but rules written this way skip anything setup would have prepared.
+func WithReadOnly() Option {
+	return func(c *Config) {
+		c.ReadOnly = true
+	}
+}
+
 // NewConfig creates a default configuration.
 func NewConfig(opts ...Option) *Config {
 	cfg := &Config{
@@ -0,0 +1,115 @@
+// Package prommetrics provides a Prometheus implementation of arangoadapter.MetricsRecorder.
+//
+// Example:
+//
+//	recorder, err := prommetrics.NewRecorder(prometheus.DefaultRegisterer)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	adapter, err := arangoadapter.NewAdapter(
+//	    arangoadapter.WithEndpoints("http://localhost:8529"),
+//	    arangoadapter.WithMetrics(recorder),
+//	)
+package prommetrics
+
+import (
+	"time"
+
+	arangoadapter "github.com/DenisBytes/arango-adapter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "casbin_arango"
+
+// Recorder exports adapter metrics to Prometheus.
+type Recorder struct {
+	duration       *prometheus.HistogramVec
+	errors         *prometheus.CounterVec
+	rulesLoaded    prometheus.Gauge
+	transactions   *prometheus.CounterVec
+	collectionSize prometheus.Gauge
+}
+
+var _ arangoadapter.MetricsRecorder = (*Recorder)(nil)
+
+// NewRecorder creates a Recorder and registers its metrics with reg.
+// constLabels are added to every metric. When several adapters share a registry, give each
+// recorder the same label names with different values (e.g. {"collection": "casbin_rule"}).
+func NewRecorder(reg prometheus.Registerer, constLabels ...prometheus.Labels) (*Recorder, error) {
+	labels := prometheus.Labels{}
+	for _, l := range constLabels {
+		for k, v := range l {
+			labels[k] = v
+		}
+	}
+
+	r := &Recorder{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "operation_duration_seconds",
+			Help:        "Latency of adapter operations.",
+			Buckets:     prometheus.DefBuckets,
+			ConstLabels: labels,
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "operation_errors_total",
+			Help:        "Failed adapter operations by operation and error code.",
+			ConstLabels: labels,
+		}, []string{"operation", "code"}),
+		rulesLoaded: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "rules_loaded",
+			Help:        "Number of rules loaded by the most recent LoadPolicy.",
+			ConstLabels: labels,
+		}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "transactions_total",
+			Help:        "Finished transactions by outcome (commit or abort).",
+			ConstLabels: labels,
+		}, []string{"outcome"}),
+		collectionSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "collection_documents",
+			Help:        "Number of documents in the policy collection.",
+			ConstLabels: labels,
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{r.duration, r.errors, r.rulesLoaded, r.transactions, r.collectionSize} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// ObserveOperation records the operation's latency and, if it failed, its error code.
+func (r *Recorder) ObserveOperation(operation string, duration time.Duration, err error) {
+	r.duration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		r.errors.WithLabelValues(operation, arangoadapter.ErrorCode(err)).Inc()
+	}
+}
+
+// ObserveRulesLoaded records how many rules the last LoadPolicy loaded.
+func (r *Recorder) ObserveRulesLoaded(count int) {
+	r.rulesLoaded.Set(float64(count))
+}
+
+// ObserveTransaction counts a committed or aborted transaction.
+func (r *Recorder) ObserveTransaction(committed bool) {
+	outcome := "abort"
+	if committed {
+		outcome = "commit"
+	}
+	r.transactions.WithLabelValues(outcome).Inc()
+}
+
+// SetCollectionSize records the number of documents in the policy collection.
+func (r *Recorder) SetCollectionSize(count int64) {
+	r.collectionSize.Set(float64(count))
+}
@@ -0,0 +1,76 @@
+package prommetrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb/shared"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecorder(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	r, err := NewRecorder(reg)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	conflict := shared.ArangoError{HasError: true, Code: 409, ErrorNum: shared.ErrArangoConflict}
+
+	r.ObserveOperation("AddPolicy", 10*time.Millisecond, nil)
+	r.ObserveOperation("AddPolicy", 10*time.Millisecond, conflict)
+	r.ObserveOperation("UpdatePolicy", 10*time.Millisecond, errors.New("boom"))
+	r.ObserveRulesLoaded(42)
+	r.ObserveTransaction(true)
+	r.ObserveTransaction(false)
+	r.ObserveTransaction(false)
+	r.SetCollectionSize(100)
+
+	expected := `
+# HELP casbin_arango_operation_errors_total Failed adapter operations by operation and error code.
+# TYPE casbin_arango_operation_errors_total counter
+casbin_arango_operation_errors_total{code="1200",operation="AddPolicy"} 1
+casbin_arango_operation_errors_total{code="unknown",operation="UpdatePolicy"} 1
+# HELP casbin_arango_rules_loaded Number of rules loaded by the most recent LoadPolicy.
+# TYPE casbin_arango_rules_loaded gauge
+casbin_arango_rules_loaded 42
+# HELP casbin_arango_transactions_total Finished transactions by outcome (commit or abort).
+# TYPE casbin_arango_transactions_total counter
+casbin_arango_transactions_total{outcome="abort"} 2
+casbin_arango_transactions_total{outcome="commit"} 1
+# HELP casbin_arango_collection_documents Number of documents in the policy collection.
+# TYPE casbin_arango_collection_documents gauge
+casbin_arango_collection_documents 100
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"casbin_arango_operation_errors_total",
+		"casbin_arango_rules_loaded",
+		"casbin_arango_transactions_total",
+		"casbin_arango_collection_documents",
+	)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count := testutil.CollectAndCount(r.duration); count != 2 {
+		t.Errorf("Expected latency series for 2 operations, got %d", count)
+	}
+}
+
+func TestRecorderDuplicateRegistration(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := NewRecorder(reg, prometheus.Labels{"collection": "casbin_rule"}); err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	// A second adapter needs distinguishing labels
+	if _, err := NewRecorder(reg, prometheus.Labels{"collection": "casbin_rule"}); err == nil {
+		t.Error("Expected duplicate registration to fail")
+	}
+	if _, err := NewRecorder(reg, prometheus.Labels{"collection": "other"}); err != nil {
+		t.Errorf("Expected registration with const labels to succeed: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package arangoadapter
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb/shared"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RetryPolicy controls how failed requests are retried.
+//
+// Only errors listed in ErrorNums or StatusCodes are retried. The defaults are limited to
+// errors where ArangoDB guarantees nothing was written (write-write conflicts and
+// unavailable coordinators), so retrying a single request can't apply it twice.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts including the first one (1 disables retries)
+	InitialBackoff time.Duration // Delay before the first retry
+	MaxBackoff     time.Duration // Upper bound for the delay between attempts
+	Multiplier     float64       // Growth factor applied to the delay after each attempt
+	Jitter         float64       // Fraction (0-1) of each delay that is randomized
+	ErrorNums      []int         // ArangoDB errorNum values to retry (e.g. 1200 for write-write conflicts)
+	StatusCodes    []int         // HTTP status codes to retry (e.g. 503 from a coordinator)
+}
+
+// DefaultRetryPolicy returns a policy that retries write-write conflicts and
+// unavailable coordinators up to 5 times with exponential backoff.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+		ErrorNums: []int{
+			shared.ErrArangoConflict,
+			shared.ErrClusterLeadershipChallengeOngoing,
+			shared.ErrClusterNotLeader,
+		},
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	}
+}
+
+// retryable reports whether err matches one of the configured error numbers or status codes.
+func (p *RetryPolicy) retryable(err error) bool {
+	if p == nil || err == nil {
+		return false
+	}
+	if len(p.ErrorNums) > 0 && shared.IsArangoErrorWithErrorNum(err, p.ErrorNums...) {
+		return true
+	}
+	for _, code := range p.StatusCodes {
+		if shared.IsArangoErrorWithCode(err, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay to wait after the given failed attempt (starting at 1).
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	// Spread retries out so concurrent writers don't collide again in lockstep
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay -= delay * jitter * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
+// withRetry runs fn and retries it according to the adapter's retry policy.
+// Requests inside a stream transaction are never retried on their own: once a request
+// fails, ArangoDB aborts the whole transaction, so only Transaction can start over.
+func (a *Adapter) withRetry(ctx context.Context, fn func() error) error {
+	if a.retryPolicy == nil || a.transaction != nil {
+		return fn()
+	}
+	return a.retryPolicy.do(ctx, a.logger, fn)
+}
+
+// do runs fn until it succeeds, fails with a non-retryable error, or runs out of attempts.
+// Every retry is logged to logger and added as an event to the current span.
+func (p *RetryPolicy) do(ctx context.Context, logger *slog.Logger, fn func() error) error {
+	attempt := 1
+	for {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("retry.attempt", attempt),
+			attribute.String("retry.error", err.Error()),
+		))
+		logger.WarnContext(ctx, "retrying request",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.Any("error", err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		attempt++
+	}
+}
@@ -0,0 +1,132 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb/shared"
+)
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	conflict := shared.ArangoError{HasError: true, Code: http.StatusConflict, ErrorNum: shared.ErrArangoConflict}
+	unavailable := shared.ArangoError{HasError: true, Code: http.StatusServiceUnavailable}
+	notFound := shared.ArangoError{HasError: true, Code: http.StatusNotFound, ErrorNum: shared.ErrArangoDocumentNotFound}
+
+	if !policy.retryable(conflict) {
+		t.Error("Write-write conflicts should be retryable")
+	}
+	if !policy.retryable(unavailable) {
+		t.Error("503 responses should be retryable")
+	}
+	if policy.retryable(notFound) {
+		t.Error("Not found errors should not be retryable")
+	}
+	if policy.retryable(errors.New("plain error")) {
+		t.Error("Non-Arango errors should not be retryable")
+	}
+	if !policy.retryable(errors.Join(conflict, errors.New("abort failed"))) {
+		t.Error("Joined errors should still be recognized")
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+		Multiplier:     2,
+	}
+
+	expected := []time.Duration{10, 20, 40, 50, 50}
+	for i, want := range expected {
+		if got := policy.backoff(i + 1); got != want*time.Millisecond {
+			t.Errorf("Attempt %d: expected %v, got %v", i+1, want*time.Millisecond, got)
+		}
+	}
+
+	// Jitter only ever shortens the delay
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := policy.backoff(1)
+		if got < 5*time.Millisecond || got > 10*time.Millisecond {
+			t.Fatalf("Jittered backoff out of range: %v", got)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxAttempts = 3
+
+	conflict := shared.ArangoError{HasError: true, Code: http.StatusConflict, ErrorNum: shared.ErrArangoConflict}
+
+	// Succeeds on the last attempt
+	calls := 0
+	err := policy.do(context.Background(), slog.New(discardHandler{}), func() error {
+		calls++
+		if calls < 3 {
+			return conflict
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success after 3 calls, got err=%v calls=%d", err, calls)
+	}
+
+	// Gives up after MaxAttempts
+	calls = 0
+	err = policy.do(context.Background(), slog.New(discardHandler{}), func() error {
+		calls++
+		return conflict
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("Expected failure after 3 calls, got err=%v calls=%d", err, calls)
+	}
+
+	// Doesn't retry other errors
+	calls = 0
+	_ = policy.do(context.Background(), slog.New(discardHandler{}), func() error {
+		calls++
+		return errors.New("boom")
+	})
+	if calls != 1 {
+		t.Errorf("Expected a single call for non-retryable errors, got %d", calls)
+	}
+}
+
+func TestAdapterWithRetry(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	a := &Adapter{retryPolicy: &policy, logger: slog.New(discardHandler{})}
+
+	conflict := shared.ArangoError{HasError: true, Code: http.StatusConflict, ErrorNum: shared.ErrArangoConflict}
+
+	calls := 0
+	_ = a.withRetry(context.Background(), func() error {
+		calls++
+		return conflict
+	})
+	if calls != policy.MaxAttempts {
+		t.Errorf("Expected %d calls outside a transaction, got %d", policy.MaxAttempts, calls)
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	first, err := newKey()
+	if err != nil {
+		t.Fatalf("Failed to make key: %v", err)
+	}
+	second, _ := newKey()
+	if first == second {
+		t.Error("Expected distinct keys")
+	}
+	if len(first) != 32 {
+		t.Errorf("Expected 32 hex characters, got %q", first)
+	}
+}
@@ -0,0 +1,191 @@
+package arangoadapter
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/arangodb/go-driver/v2/arangodb"
+)
+
+// routing knows which collection holds the rules of each ptype. Ptypes without a route
+// of their own or for their section use the adapter's main collection.
+type routing struct {
+	main     string
+	ptypes   map[string]string // Collection by ptype
+	sections map[string]string // Collection by section, for ptypes without their own
+}
+
+// newRouting validates the configured collection routes.
+func newRouting(cfg *Config) (*routing, error) {
+	r := &routing{
+		main:     cfg.CollectionName,
+		ptypes:   cfg.PtypeCollections,
+		sections: cfg.SectionCollections,
+	}
+	for _, routes := range []map[string]string{r.ptypes, r.sections} {
+		for key, name := range routes {
+			if key == "" || name == "" {
+				return nil, fmt.Errorf("invalid collection route %q to %q", key, name)
+			}
+		}
+	}
+
+	records := make(map[string]bool)
+	for _, name := range recordCollections(cfg) {
+		if records[name] {
+			return nil, fmt.Errorf("collection %q can't hold more than one kind of record", name)
+		}
+		records[name] = true
+	}
+	for _, name := range r.names() {
+		if records[name] {
+			return nil, fmt.Errorf("collection %q can't hold rules and records", name)
+		}
+	}
+	return r, nil
+}
+
+// recordCollections returns the collections cfg keeps the audit log, history and metadata
+// in, with their default names filled in.
+func recordCollections(cfg *Config) []string {
+	var names []string
+	if cfg.AuditEnabled {
+		names = append(names, cmp.Or(cfg.AuditCollectionName, cfg.CollectionName+"_audit"))
+	}
+	if cfg.VersioningEnabled {
+		names = append(names, cmp.Or(cfg.HistoryCollectionName, cfg.CollectionName+"_history"))
+	}
+	if !cfg.NoMetaCollection {
+		names = append(names, metaCollectionName(cfg))
+	}
+	return names
+}
+
+// sectionOf returns the section a ptype belongs to, e.g. "g" for "g2".
+func sectionOf(ptype string) string {
+	if ptype == "" {
+		return ""
+	}
+	return ptype[:1]
+}
+
+// collectionFor returns the name of the collection holding ptype's rules.
+func (r *routing) collectionFor(ptype string) string {
+	if name, ok := r.ptypes[ptype]; ok {
+		return name
+	}
+	if name, ok := r.sections[sectionOf(ptype)]; ok {
+		return name
+	}
+	return r.main
+}
+
+// names returns every collection holding rules, the main one first.
+func (r *routing) names() []string {
+	seen := map[string]bool{r.main: true}
+	var routed []string
+	for _, routes := range []map[string]string{r.ptypes, r.sections} {
+		for _, name := range routes {
+			if !seen[name] {
+				seen[name] = true
+				routed = append(routed, name)
+			}
+		}
+	}
+	sort.Strings(routed)
+	return append([]string{r.main}, routed...)
+}
+
+// namesFor returns the collections that can hold rules of the given ptypes,
+// or every collection if ptypes is empty.
+func (r *routing) namesFor(ptypes []string) []string {
+	if len(ptypes) == 0 {
+		return r.names()
+	}
+
+	wanted := make(map[string]bool)
+	for _, ptype := range ptypes {
+		wanted[r.collectionFor(ptype)] = true
+	}
+	var names []string
+	for _, name := range r.names() {
+		if wanted[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ensureRuleCollections gets or creates every collection holding rules, with their indexes.
+func (a *Adapter) ensureRuleCollections(ctx context.Context) error {
+	a.collections = make(map[string]arangodb.Collection)
+	schema := a.validationSchema()
+	for _, name := range a.routing.names() {
+		col, err := a.ensureCollection(ctx, name, &arangodb.CreateCollectionProperties{Schema: schema})
+		if err != nil {
+			return err
+		}
+		a.collections[name] = col
+		if a.readOnly {
+			continue
+		}
+		// Existing collections get the schema too, in case validation was turned on later
+		// or the field mapping changed
+		if schema != nil {
+			if err := col.SetProperties(ctx, arangodb.SetCollectionPropertiesOptions{Schema: schema}); err != nil {
+				return err
+			}
+		}
+		if a.expiry {
+			if err := a.ensureExpiryIndex(ctx, col); err != nil {
+				return err
+			}
+		}
+		if a.softDelete {
+			if err := a.ensureDeletedIndex(ctx, col); err != nil {
+				return err
+			}
+		}
+		if err := a.ensureSortIndexes(ctx, col); err != nil {
+			return err
+		}
+	}
+	a.collection = a.collections[a.collectionName]
+	return nil
+}
+
+// eachRule calls fn with every rule matching conditions in the named collections.
+func (a *Adapter) eachRule(ctx context.Context, names []string, conditions string, bindVars map[string]interface{}, fn func(rule CasbinRule) error) error {
+	query := "FOR doc IN @@collection FILTER " + conditions + " RETURN doc"
+	for _, name := range names {
+		bindVars["@collection"] = name
+		if err := a.readRules(ctx, query, bindVars, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRules runs query and calls fn with every rule it returns.
+func (a *Adapter) readRules(ctx context.Context, query string, bindVars map[string]interface{}, fn func(rule CasbinRule) error) error {
+	cursor, err := a.query(ctx, query, bindVars)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
+
+	for cursor.HasMore() {
+		rule, err := a.readRule(ctx, cursor)
+		if err != nil {
+			return err
+		}
+		if err := fn(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package arangoadapter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRouting(t *testing.T) {
+	r, err := newRouting(NewConfig(
+		WithCollection("rules"),
+		WithSectionCollection("g", "groupings"),
+		WithPtypeCollection("p2", "tenant_policies"),
+		WithPtypeCollection("g3", "rules"),
+	))
+	if err != nil {
+		t.Fatalf("Failed to create routing: %v", err)
+	}
+
+	expected := map[string]string{
+		"p":  "rules",
+		"p2": "tenant_policies",
+		"g":  "groupings",
+		"g2": "groupings",
+		"g3": "rules",
+	}
+	for ptype, name := range expected {
+		if got := r.collectionFor(ptype); got != name {
+			t.Errorf("Expected %s rules in %s, got %s", ptype, name, got)
+		}
+	}
+
+	if names := r.names(); !reflect.DeepEqual(names, []string{"rules", "groupings", "tenant_policies"}) {
+		t.Errorf("Unexpected collections: %v", names)
+	}
+	if names := r.namesFor([]string{"g", "g2"}); !reflect.DeepEqual(names, []string{"groupings"}) {
+		t.Errorf("Unexpected collections for g: %v", names)
+	}
+
+	if _, err := newRouting(NewConfig(WithAudit("audit"), WithSectionCollection("g", "audit"))); err == nil {
+		t.Error("Routing rules to the audit collection should be rejected")
+	}
+
+	rejected := []*Config{
+		NewConfig(WithCollection("rules"), WithVersioning(""), WithSectionCollection("g", "rules_history")),
+		NewConfig(WithCollection("rules"), WithAudit(""), WithPtypeCollection("p2", "rules_audit")),
+		NewConfig(WithCollection("rules"), WithSectionCollection("g", "rules_meta")),
+		NewConfig(WithCollection("rules"), WithMetaCollection("rules")),
+		NewConfig(WithAudit("records"), WithVersioning("records")),
+	}
+	for _, cfg := range rejected {
+		if _, err := newRouting(cfg); err == nil {
+			t.Errorf("Expected colliding collections to be rejected: %+v", cfg)
+		}
+	}
+	if _, err := newRouting(NewConfig(WithCollection("rules"), WithoutMetaCollection(), WithSectionCollection("g", "rules_meta"))); err != nil {
+		t.Errorf("Without a metadata collection its name is free: %v", err)
+	}
+}
+
+func TestCollectionRoutingIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t,
+		WithSectionCollection("g", "casbin_groupings_test"),
+		WithAudit(""),
+	)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = adapter.AddPolicy("g", "g", []string{"alice", "admin"})
+
+	groupings := adapter.collections["casbin_groupings_test"]
+	if count, _ := groupings.Count(ctx); count != 1 {
+		t.Errorf("Expected the grouping in its own collection, got %d documents", count)
+	}
+	if count, _ := adapter.collection.Count(ctx); count != 1 {
+		t.Errorf("Expected only the policy in the main collection, got %d documents", count)
+	}
+
+	m := newTestModel()
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if ok, _ := m.HasPolicy("g", "g", []string{"alice", "admin"}); !ok {
+		t.Error("Grouping should load from its collection")
+	}
+
+	m = newTestModel()
+	if err := adapter.LoadFilteredPolicy(m, Filter{Ptype: []string{"g"}}); err != nil {
+		t.Fatalf("Failed to load filtered policy: %v", err)
+	}
+	if policies, _ := m.GetPolicy("p", "p"); len(policies) != 0 {
+		t.Errorf("Filter on g shouldn't load policies, got %v", policies)
+	}
+
+	// Save goes through a transaction declaring both collections, since auditing is on
+	m = newTestModel()
+	_ = m.AddPolicy("p", "p", []string{"bob", "data2", "write"})
+	_ = m.AddPolicy("g", "g", []string{"bob", "admin"})
+	_ = m.AddPolicy("g", "g", []string{"carol", "admin"})
+	if err := adapter.SavePolicy(m); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+	if count, _ := groupings.Count(ctx); count != 2 {
+		t.Errorf("Expected 2 groupings after save, got %d", count)
+	}
+
+	if err := adapter.RemoveFilteredPolicy("g", "g", 1, "admin"); err != nil {
+		t.Fatalf("Failed to remove groupings: %v", err)
+	}
+	if count, _ := groupings.Count(ctx); count != 0 {
+		t.Errorf("Expected groupings removed, got %d", count)
+	}
+}
@@ -0,0 +1,374 @@
+package arangoadapter
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb"
+	"github.com/arangodb/go-driver/v2/arangodb/shared"
+)
+
+// SchemaVersion is the version of the document layout this release of the adapter reads and writes.
+const SchemaVersion = 1
+
+var (
+	// ErrSchemaOutdated is returned by NewAdapter with WithStrictSchema when the stored rules
+	// use an older layout and need migrating first.
+	ErrSchemaOutdated = errors.New("policy collection uses an older schema version")
+
+	// ErrSchemaTooNew is returned by NewAdapter when the stored rules were migrated by a newer
+	// release of the adapter, or with migrations this adapter doesn't know about.
+	ErrSchemaTooNew = errors.New("policy collection uses a newer schema version")
+)
+
+const (
+	schemaKey      = "schema"      // Metadata document holding the schema version
+	schemaLockKey  = "schema_lock" // Metadata document held while migrating
+	schemaLockTTL  = 10 * time.Minute
+	schemaLockBeat = schemaLockTTL / 3 // How often a held lock is extended
+	schemaLockPoll = time.Second
+)
+
+// SchemaMigration upgrades the stored rules from the version before Version to Version.
+// Versions up to SchemaVersion belong to the adapter itself, register your own from there on.
+type SchemaMigration struct {
+	Version     int    // Version the migration upgrades to
+	Description string // Logged when the migration runs
+
+	// Migrate does the work, given the database and the names of every rule collection.
+	// It should be safe to run again in case it's interrupted.
+	Migrate func(ctx context.Context, db arangodb.Database, collections []string) error
+}
+
+// builtinMigrations upgrade the layouts of earlier releases.
+var builtinMigrations = []SchemaMigration{
+	{
+		Version:     1,
+		Description: "start tracking the schema version",
+		Migrate: func(context.Context, arangodb.Database, []string) error {
+			// Rules stored before the version was tracked already have this layout
+			return nil
+		},
+	},
+}
+
+// schemaDocument is the metadata document recording the schema version.
+type schemaDocument struct {
+	Version   int    `json:"version"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// schemaPlan returns the built-in and registered migrations by version, along with the
+// version they lead up to. Every version up to that one needs exactly one migration.
+func schemaPlan(registered []SchemaMigration) (map[int]SchemaMigration, int, error) {
+	plan := make(map[int]SchemaMigration)
+	for _, m := range builtinMigrations {
+		plan[m.Version] = m
+	}
+
+	target := SchemaVersion
+	for _, m := range registered {
+		if m.Version <= SchemaVersion {
+			return nil, 0, fmt.Errorf("schema version %d belongs to the adapter, register migrations to versions above %d", m.Version, SchemaVersion)
+		}
+		if m.Migrate == nil {
+			return nil, 0, fmt.Errorf("migration to schema version %d has no Migrate function", m.Version)
+		}
+		if _, ok := plan[m.Version]; ok {
+			return nil, 0, fmt.Errorf("more than one migration to schema version %d", m.Version)
+		}
+		plan[m.Version] = m
+		target = max(target, m.Version)
+	}
+
+	for version := 1; version <= target; version++ {
+		if _, ok := plan[version]; !ok {
+			return nil, 0, fmt.Errorf("no migration to schema version %d", version)
+		}
+	}
+	return plan, target, nil
+}
+
+// metaCollectionName returns the name of the metadata collection, by default the policy
+// collection name with a "_meta" suffix.
+func metaCollectionName(cfg *Config) string {
+	return cmp.Or(cfg.MetaCollectionName, cfg.CollectionName+"_meta")
+}
+
+// ensureSchema makes sure the stored rules use the schema version this adapter expects,
+// running the pending migrations under a lock unless cfg asks for a strict check.
+// The version is kept in the metadata collection, unless cfg opts out of tracking it.
+func (a *Adapter) ensureSchema(ctx context.Context, cfg *Config) error {
+	if cfg.NoMetaCollection {
+		if len(cfg.SchemaMigrations) > 0 {
+			return errors.New("schema migrations need the metadata collection")
+		}
+		return nil
+	}
+	plan, target, err := schemaPlan(cfg.SchemaMigrations)
+	if err != nil {
+		return err
+	}
+
+	name := metaCollectionName(cfg)
+	if a.readOnly {
+		return a.checkSchema(ctx, name, target)
+	}
+	col, err := a.ensureCollection(ctx, name, nil)
+	if err != nil {
+		return err
+	}
+	a.metaCollection = col
+	a.metaCollectionName = name
+
+	version, found, err := a.readSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if !found {
+		empty, err := a.rulesEmpty(ctx)
+		if err != nil {
+			return err
+		}
+		// Nothing stored yet, so there's nothing to migrate either
+		if empty {
+			return a.setSchemaVersion(ctx, target)
+		}
+	}
+
+	switch {
+	case version == target:
+		return nil
+	case version > target:
+		return fmt.Errorf("%w: version %d, this adapter supports up to %d", ErrSchemaTooNew, version, target)
+	case cfg.StrictSchema:
+		return fmt.Errorf("%w: version %d, this adapter needs %d", ErrSchemaOutdated, version, target)
+	}
+
+	release, err := a.lockSchema(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Another adapter may have migrated while this one waited for the lock
+	if version, _, err = a.readSchemaVersion(ctx); err != nil {
+		return err
+	}
+	if version > target {
+		return fmt.Errorf("%w: version %d, this adapter supports up to %d", ErrSchemaTooNew, version, target)
+	}
+
+	for version++; version <= target; version++ {
+		m := plan[version]
+		a.logger.InfoContext(ctx, "migrating schema",
+			slog.Int("version", version),
+			slog.String("description", m.Description),
+		)
+		if err := m.Migrate(ctx, a.db, a.routing.names()); err != nil {
+			return fmt.Errorf("migrating to schema version %d: %w", version, err)
+		}
+		if err := a.setSchemaVersion(ctx, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSchema is ensureSchema for read-only adapters, which can't record or migrate
+// anything. Rules in an older layout are read anyway, with a warning.
+func (a *Adapter) checkSchema(ctx context.Context, name string, target int) error {
+	col, err := a.db.Collection(ctx, name)
+	if err != nil && !shared.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		a.metaCollection = col
+		a.metaCollectionName = name
+	}
+
+	version, _, err := a.readSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version > target {
+		return fmt.Errorf("%w: version %d, this adapter supports up to %d", ErrSchemaTooNew, version, target)
+	}
+	if version < target {
+		a.logger.WarnContext(ctx, "policy collection uses an older schema version, open it without WithReadOnly to migrate",
+			slog.Int("version", version),
+			slog.Int("expected", target),
+		)
+	}
+	return nil
+}
+
+// StoredSchemaVersion returns the schema version recorded for the stored rules, or 0 if
+// there's none, e.g. with WithoutMetaCollection.
+func (a *Adapter) StoredSchemaVersion(ctx context.Context) (int, error) {
+	version, _, err := a.readSchemaVersion(ctx)
+	return version, err
+}
+
+// readSchemaVersion returns the recorded schema version. Rules stored before the version
+// was tracked have none, which counts as version 0.
+func (a *Adapter) readSchemaVersion(ctx context.Context) (int, bool, error) {
+	if a.metaCollection == nil {
+		return 0, false, nil
+	}
+	var doc schemaDocument
+	if _, err := a.metaCollection.ReadDocument(ctx, schemaKey, &doc); err != nil {
+		if shared.IsNotFound(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return doc.Version, true, nil
+}
+
+// setSchemaVersion records version as the schema version of the stored rules.
+func (a *Adapter) setSchemaVersion(ctx context.Context, version int) error {
+	query := "UPSERT { _key: @key } INSERT { _key: @key, version: @version, updatedAt: @now }" +
+		" UPDATE { version: @version, updatedAt: @now } IN @@meta"
+	cursor, err := a.query(ctx, query, map[string]interface{}{
+		"@meta":   a.metaCollectionName,
+		"key":     schemaKey,
+		"version": version,
+		"now":     formatTime(time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+	return cursor.Close()
+}
+
+// rulesEmpty reports whether every rule collection is empty.
+func (a *Adapter) rulesEmpty(ctx context.Context) (bool, error) {
+	for _, name := range a.routing.names() {
+		count, err := a.collections[name].Count(ctx)
+		if err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// lockSchema waits until it holds the schema lock, so only one adapter migrates at a time,
+// and returns a function releasing it. The lock is extended every schemaLockBeat while it's
+// held, so long migrations keep it. A lock that isn't released or extended, e.g. because its
+// holder crashed, is taken over once it's older than schemaLockTTL.
+func (a *Adapter) lockSchema(ctx context.Context) (func(), error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	owner := hex.EncodeToString(id)
+
+	query := "UPSERT { _key: @key } INSERT { _key: @key, owner: @owner, until: @until }" +
+		" UPDATE (OLD.until < @now ? { owner: @owner, until: @until } : {}) IN @@meta RETURN NEW.owner"
+	for waiting := false; ; waiting = true {
+		now := time.Now()
+		holder, err := a.claimSchemaLock(ctx, query, map[string]interface{}{
+			"@meta": a.metaCollectionName,
+			"key":   schemaLockKey,
+			"owner": owner,
+			"now":   formatTime(now),
+			"until": formatTime(now.Add(schemaLockTTL)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if holder == owner {
+			break
+		}
+
+		if !waiting {
+			a.logger.InfoContext(ctx, "waiting for another adapter to finish migrating")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(schemaLockPoll):
+		}
+	}
+
+	beatCtx, stopBeat := context.WithCancel(context.WithoutCancel(ctx))
+	beating := make(chan struct{})
+	go func() {
+		defer close(beating)
+		ticker := time.NewTicker(schemaLockBeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-beatCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := a.renewSchemaLock(beatCtx, owner); err != nil && beatCtx.Err() == nil {
+				a.logger.WarnContext(ctx, "failed to extend schema lock", slog.Any("error", err))
+			}
+		}
+	}()
+
+	return func() {
+		stopBeat()
+		<-beating
+
+		query := "FOR doc IN @@meta FILTER doc._key == @key && doc.owner == @owner REMOVE doc IN @@meta"
+		cursor, err := a.query(context.WithoutCancel(ctx), query, map[string]interface{}{
+			"@meta": a.metaCollectionName,
+			"key":   schemaLockKey,
+			"owner": owner,
+		})
+		if err != nil {
+			a.logger.WarnContext(ctx, "failed to release schema lock", slog.Any("error", err))
+			return
+		}
+		_ = cursor.Close()
+	}, nil
+}
+
+// renewSchemaLock extends the schema lock if owner still holds it.
+func (a *Adapter) renewSchemaLock(ctx context.Context, owner string) error {
+	query := "FOR doc IN @@meta FILTER doc._key == @key && doc.owner == @owner UPDATE doc WITH { until: @until } IN @@meta"
+	cursor, err := a.query(ctx, query, map[string]interface{}{
+		"@meta": a.metaCollectionName,
+		"key":   schemaLockKey,
+		"owner": owner,
+		"until": formatTime(time.Now().Add(schemaLockTTL)),
+	})
+	if err != nil {
+		return err
+	}
+	return cursor.Close()
+}
+
+// claimSchemaLock runs the query claiming the schema lock and returns the lock's holder.
+// Losing a race for it to another adapter returns no holder.
+func (a *Adapter) claimSchemaLock(ctx context.Context, query string, bindVars map[string]interface{}) (string, error) {
+	cursor, err := a.query(ctx, query, bindVars)
+	if shared.IsConflict(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
+
+	var holder string
+	if _, err := cursor.ReadDocument(ctx, &holder); err != nil {
+		return "", err
+	}
+	return holder, nil
+}
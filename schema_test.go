@@ -0,0 +1,200 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb"
+)
+
+// noopMigration returns a migration to version that does nothing.
+func noopMigration(version int) SchemaMigration {
+	return SchemaMigration{
+		Version: version,
+		Migrate: func(context.Context, arangodb.Database, []string) error { return nil },
+	}
+}
+
+func TestSchemaPlan(t *testing.T) {
+	plan, target, err := schemaPlan(nil)
+	if err != nil || target != SchemaVersion || len(plan) != SchemaVersion {
+		t.Fatalf("Unexpected plan without migrations: %d migrations to %d, %v", len(plan), target, err)
+	}
+
+	_, target, err = schemaPlan([]SchemaMigration{noopMigration(SchemaVersion + 2), noopMigration(SchemaVersion + 1)})
+	if err != nil || target != SchemaVersion+2 {
+		t.Errorf("Expected registered migrations to raise the target, got %d, %v", target, err)
+	}
+
+	invalid := map[string][]SchemaMigration{
+		"built-in version": {noopMigration(SchemaVersion)},
+		"gap":              {noopMigration(SchemaVersion + 2)},
+		"duplicate":        {noopMigration(SchemaVersion + 1), noopMigration(SchemaVersion + 1)},
+		"no function":      {{Version: SchemaVersion + 1}},
+	}
+	for name, migrations := range invalid {
+		if _, _, err := schemaPlan(migrations); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestSchemaVersionIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	// A fresh collection starts out at the current version
+	if version, err := adapter.StoredSchemaVersion(ctx); err != nil || version != SchemaVersion {
+		t.Fatalf("Expected version %d, got %d, %v", SchemaVersion, version, err)
+	}
+
+	// Rules from before the version was tracked
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	if err := adapter.metaCollection.Truncate(ctx); err != nil {
+		t.Fatalf("Failed to clear metadata: %v", err)
+	}
+
+	if _, err := NewAdapterFromClient(adapter.client, adapter.databaseName, adapter.collectionName, WithStrictSchema()); !errors.Is(err, ErrSchemaOutdated) {
+		t.Errorf("Expected ErrSchemaOutdated in strict mode, got %v", err)
+	}
+
+	var migrated []string
+	migration := SchemaMigration{
+		Version:     SchemaVersion + 1,
+		Description: "rename alice",
+		Migrate: func(ctx context.Context, db arangodb.Database, collections []string) error {
+			migrated = collections
+			cursor, err := db.Query(ctx, "FOR doc IN @@collection FILTER doc.v0 == 'alice' UPDATE doc WITH { v0: 'alice.smith' } IN @@collection",
+				&arangodb.QueryOptions{BindVars: map[string]interface{}{"@collection": collections[0]}})
+			if err != nil {
+				return err
+			}
+			return cursor.Close()
+		},
+	}
+	upgraded, err := NewAdapterFromClient(adapter.client, adapter.databaseName, adapter.collectionName, WithSchemaMigration(migration))
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if len(migrated) != 1 || migrated[0] != adapter.collectionName {
+		t.Errorf("Migration should get the rule collections, got %v", migrated)
+	}
+	if version, _ := upgraded.StoredSchemaVersion(ctx); version != SchemaVersion+1 {
+		t.Errorf("Expected version %d after migrating, got %d", SchemaVersion+1, version)
+	}
+	if rules, _ := upgraded.GetRules(ctx, Filter{V0: []string{"alice.smith"}}); len(rules) != 1 {
+		t.Errorf("Expected the migration to rename the rule, got %v", rules)
+	}
+	if _, err := upgraded.metaCollection.ReadDocument(ctx, schemaLockKey, &map[string]interface{}{}); err == nil {
+		t.Error("Schema lock should be released after migrating")
+	}
+
+	// The adapter without the migration can't read what it wrote
+	if _, err := NewAdapterFromClient(adapter.client, adapter.databaseName, adapter.collectionName); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestSchemaLockIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	// A lock held by someone else blocks until the context is done
+	held := map[string]interface{}{"_key": schemaLockKey, "owner": "other", "until": formatTime(time.Now().Add(time.Hour))}
+	if _, err := adapter.metaCollection.CreateDocument(ctx, held); err != nil {
+		t.Fatalf("Failed to create lock: %v", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err := adapter.lockSchema(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected to wait for the held lock, got %v", err)
+	}
+
+	// An expired lock is taken over
+	stale := map[string]interface{}{"until": formatTime(time.Now().Add(-time.Minute))}
+	if _, err := adapter.metaCollection.UpdateDocument(ctx, schemaLockKey, stale); err != nil {
+		t.Fatalf("Failed to expire lock: %v", err)
+	}
+	release, err := adapter.lockSchema(ctx)
+	if err != nil {
+		t.Fatalf("Expected to take over the expired lock: %v", err)
+	}
+
+	// Holding it keeps extending it
+	var lock struct {
+		Owner string `json:"owner"`
+		Until string `json:"until"`
+	}
+	if _, err := adapter.metaCollection.ReadDocument(ctx, schemaLockKey, &lock); err != nil {
+		t.Fatalf("Failed to read lock: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := adapter.renewSchemaLock(ctx, lock.Owner); err != nil {
+		t.Fatalf("Failed to extend lock: %v", err)
+	}
+	until := lock.Until
+	_, _ = adapter.metaCollection.ReadDocument(ctx, schemaLockKey, &lock)
+	if lock.Until <= until {
+		t.Errorf("Expected the lock to be extended past %s, got %s", until, lock.Until)
+	}
+	release()
+	if _, err := adapter.metaCollection.ReadDocument(ctx, schemaLockKey, &map[string]interface{}{}); err == nil {
+		t.Error("Lock should be removed on release")
+	}
+}
+
+func TestReadOnlyIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	if _, err := NewAdapterFromClient(adapter.client, adapter.databaseName, "casbin_rule_missing", WithReadOnly()); err == nil {
+		t.Error("Expected an error for a collection that doesn't exist")
+	}
+	if exists, _ := adapter.db.CollectionExists(ctx, "casbin_rule_missing"); exists {
+		t.Error("Read-only adapter shouldn't create collections")
+	}
+
+	_ = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	if _, err := NewAdapterFromClient(adapter.client, adapter.databaseName, adapter.collectionName, WithReadOnly(), WithAudit("")); err == nil {
+		t.Error("Expected an error for the missing audit collection")
+	}
+	reader, err := NewAdapterFromClient(adapter.client, adapter.databaseName, adapter.collectionName, WithReadOnly())
+	if err != nil {
+		t.Fatalf("Failed to open read-only: %v", err)
+	}
+	if count, err := reader.CountRules(ctx, Filter{}); err != nil || count != 1 {
+		t.Errorf("Expected 1 rule, got %d, %v", count, err)
+	}
+}
+
+func TestMetaCollectionIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t, WithMetaCollection("casbin_meta_test"))
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	if adapter.metaCollectionName != "casbin_meta_test" {
+		t.Errorf("Expected the configured metadata collection, got %q", adapter.metaCollectionName)
+	}
+	if exists, _ := adapter.db.CollectionExists(ctx, adapter.collectionName+"_meta"); exists {
+		t.Error("Default metadata collection shouldn't be created")
+	}
+
+	untracked, err := NewAdapterFromClient(adapter.client, adapter.databaseName, "casbin_untracked_test", WithoutMetaCollection())
+	if err != nil {
+		t.Fatalf("Failed to open without metadata: %v", err)
+	}
+	if exists, _ := adapter.db.CollectionExists(ctx, "casbin_untracked_test_meta"); exists {
+		t.Error("Opting out shouldn't create a metadata collection")
+	}
+	if version, err := untracked.StoredSchemaVersion(ctx); err != nil || version != 0 {
+		t.Errorf("Expected no version, got %d, %v", version, err)
+	}
+	if _, err := NewAdapterFromClient(adapter.client, adapter.databaseName, "casbin_untracked_test", WithoutMetaCollection(), WithSchemaMigration(noopMigration(SchemaVersion+1))); err == nil {
+		t.Error("Expected migrations without metadata to be rejected")
+	}
+}
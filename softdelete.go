@@ -0,0 +1,122 @@
+package arangoadapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/arangodb/go-driver/v2/arangodb"
+)
+
+// notDeleted matches documents that haven't been soft deleted. It's applied even with soft
+// delete turned off, so switching it off later doesn't bring removed rules back.
+const notDeleted = "doc.deletedAt == null"
+
+// ensureDeletedIndex adds a sparse index for finding soft deleted rules by deletion time.
+func (a *Adapter) ensureDeletedIndex(ctx context.Context, col arangodb.Collection) error {
+	sparse := true
+	_, _, err := col.EnsurePersistentIndex(ctx, []string{"deletedAt"}, &arangodb.CreatePersistentIndexOptions{
+		Sparse: &sparse,
+	})
+	return err
+}
+
+// RestoreRemovedPolicy brings back soft deleted rules matching the partial filter, using the
+// same matching as RemoveFilteredPolicy, and returns them. A rule removed more than once is
+// restored from its latest removal, and rules that have been added again are left alone.
+// Add them to the enforcer's model or reload its policy afterwards. With WithErrOnNoMatch
+// enabled it returns ErrRuleNotFound when nothing matched.
+func (a *Adapter) RestoreRemovedPolicy(ctx context.Context, sec string, ptype string, fieldIndex int, fieldValues ...string) (restored []CasbinRule, err error) {
+	ctx, op := a.startWrite(ctx, "RestoreRemovedPolicy", attrSection.String(sec), attrPtype.String(ptype))
+	defer func() {
+		op.setRuleCount(len(restored))
+		op.end(err)
+	}()
+
+	conditions, bindVars, err := a.mapping.filteredConditions(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return nil, err
+	}
+	err = a.recorded(ctx, func(tx *Adapter) error {
+		// Start over if the transaction is retried
+		restored = nil
+
+		bindVars["@collection"] = tx.routing.collectionFor(ptype)
+
+		// Values may be encoded differently in each copy, so rules are compared after decoding
+		live := make(map[string]bool)
+		err := tx.readRules(ctx, "FOR doc IN @@collection FILTER "+conditions+" && "+notDeleted+" RETURN doc", bindVars, func(rule CasbinRule) error {
+			live[rule.identity()] = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		keys := []string{}
+		query := "FOR doc IN @@collection FILTER " + conditions + " && doc.deletedAt != null SORT doc.deletedAt DESC RETURN doc"
+		err = tx.readRules(ctx, query, bindVars, func(rule CasbinRule) error {
+			if !live[rule.identity()] {
+				live[rule.identity()] = true
+				keys = append(keys, rule.Key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		query = "FOR doc IN @@collection FILTER doc._key IN @keys" +
+			" UPDATE doc WITH { deletedAt: null, deletedBy: null } IN @@collection OPTIONS { keepNull: false } RETURN NEW"
+		cursor, err := tx.query(ctx, query, map[string]interface{}{
+			"@collection": bindVars["@collection"],
+			"keys":        keys,
+		})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = cursor.Close()
+		}()
+
+		for cursor.HasMore() {
+			rule, err := tx.readRule(ctx, cursor)
+			if err != nil {
+				return err
+			}
+			restored = append(restored, rule)
+		}
+
+		if len(restored) == 0 && tx.errOnNoMatch {
+			return ErrRuleNotFound
+		}
+		return tx.record(ctx, change{operation: AuditRestoreRemovedPolicy, added: restored})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// PurgeRemovedBefore permanently deletes rules that were soft deleted before the given time
+// and reports how many it deleted.
+func (a *Adapter) PurgeRemovedBefore(ctx context.Context, before time.Time) (count int64, err error) {
+	ctx, op := a.startWrite(ctx, "PurgeRemovedBefore")
+	defer func() {
+		op.setRuleCount(int(count))
+		op.end(err)
+	}()
+
+	query := "FOR doc IN @@collection FILTER doc.deletedAt != null && doc.deletedAt < @before REMOVE doc IN @@collection"
+	bindVars := map[string]interface{}{
+		"before": formatTime(before),
+	}
+
+	for _, name := range a.routing.names() {
+		bindVars["@collection"] = name
+		_, purged, err := a.writeQuery(ctx, query, bindVars, false)
+		if err != nil {
+			return count, err
+		}
+		count += purged
+	}
+	return count, nil
+}
@@ -0,0 +1,117 @@
+package arangoadapter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSoftDelete(t *testing.T) {
+	adapter := setupTestAdapter(t, WithSoftDelete())
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := WithActor(context.Background(), "admin")
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data2", "read"})
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data1", "read"})
+
+	removed, err := adapter.RemoveFilteredPolicyReturning(ctx, "p", "p", 0, "alice")
+	if err != nil {
+		t.Fatalf("Failed to remove policies: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Fatalf("Expected 2 removed rules, got %d", len(removed))
+	}
+
+	count, _ := adapter.collection.Count(ctx)
+	if count != 3 {
+		t.Errorf("Soft deleted rules should stay in the collection, got %d documents", count)
+	}
+
+	m := newTestModel()
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	policies, _ := m.GetPolicy("p", "p")
+	if len(policies) != 1 || policies[0][0] != "bob" {
+		t.Errorf("Expected only bob's rule to load, got %v", policies)
+	}
+
+	m = newTestModel()
+	if err := adapter.LoadFilteredPolicyCtx(ctx, m, Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatalf("Failed to load filtered policy: %v", err)
+	}
+	if policies, _ := m.GetPolicy("p", "p"); len(policies) != 0 {
+		t.Errorf("Filtered load shouldn't return soft deleted rules, got %v", policies)
+	}
+
+	// Removing again doesn't match the flagged rules
+	if n, _ := adapter.RemoveFilteredPolicyCount(ctx, "p", "p", 0, "alice"); n != 0 {
+		t.Errorf("Expected soft deleted rules to be skipped, removed %d", n)
+	}
+
+	restored, err := adapter.RestoreRemovedPolicy(ctx, "p", "p", 0, "alice", "data1")
+	if err != nil {
+		t.Fatalf("Failed to restore policy: %v", err)
+	}
+	if len(restored) != 1 || restored[0].V1 != "data1" {
+		t.Errorf("Expected alice's data1 rule back, got %v", restored)
+	}
+
+	purged, err := adapter.PurgeRemovedBefore(ctx, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("Failed to purge removed rules: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected alice's data2 rule to be purged, got %d", purged)
+	}
+	if count, _ := adapter.collection.Count(ctx); count != 2 {
+		t.Errorf("Expected 2 documents after purging, got %d", count)
+	}
+}
+
+func TestRestoreRemovedPolicyOncePerRule(t *testing.T) {
+	adapter := setupTestAdapter(t, WithSoftDelete())
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	// alice's rule is removed twice, bob's is removed and added again
+	for i := 0; i < 2; i++ {
+		_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+		_ = adapter.RemovePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+	}
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data1", "read"})
+	_ = adapter.RemovePolicyCtx(ctx, "p", "p", []string{"bob", "data1", "read"})
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data1", "read"})
+
+	restored, err := adapter.RestoreRemovedPolicy(ctx, "p", "p", 1, "data1")
+	if err != nil {
+		t.Fatalf("Failed to restore policy: %v", err)
+	}
+	if len(restored) != 1 || restored[0].V0 != "alice" {
+		t.Errorf("Expected one copy of alice's rule back, got %v", restored)
+	}
+	if rules, _ := adapter.GetRules(ctx, Filter{}); len(rules) != 2 {
+		t.Errorf("Expected one live rule each for alice and bob, got %v", rules)
+	}
+}
+
+func TestSoftDeleteSavePolicyKeepsRemoved(t *testing.T) {
+	adapter := setupTestAdapter(t, WithSoftDelete())
+	defer teardownTestAdapter(t, adapter)
+
+	ctx := context.Background()
+	_ = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+	_ = adapter.RemovePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+
+	m := newTestModel()
+	_ = m.AddPolicy("p", "p", []string{"bob", "data1", "read"})
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+
+	restored, err := adapter.RestoreRemovedPolicy(ctx, "p", "p", 0, "alice")
+	if err != nil || len(restored) != 1 {
+		t.Errorf("Removed rule should survive SavePolicy, got %v (%v)", restored, err)
+	}
+}
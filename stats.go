@@ -0,0 +1,184 @@
+package arangoadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/arangodb/go-driver/v2/arangodb/shared"
+	"github.com/arangodb/go-driver/v2/connection"
+)
+
+// statsTopRoles is how many roles Stats returns in TopRoles.
+const statsTopRoles = 10
+
+// Stats describes the stored policy.
+type Stats struct {
+	Rules        int64            // Stored rules, not counting soft deleted ones
+	RulesByPtype map[string]int64 // Rules per ptype, with "" for documents without one
+	Subjects     int64            // Distinct first values (v0) of policy rules
+	Objects      int64            // Distinct second values (v1) of policy rules
+	TopRoles     []RoleCount      // The roles with the most members, most first
+	SizeBytes    int64            // Disk space used by the policy collections' documents and indexes
+}
+
+// RoleCount is the number of members of a role.
+type RoleCount struct {
+	Ptype   string // The grouping ptype, e.g. "g"
+	Role    string
+	Members int64
+}
+
+// Stats counts the stored rules with AQL aggregations, without loading them. Policy rules
+// are the ones whose ptype starts with "p", grouping rules the ones starting with "g", the
+// way Casbin names them. Values are compared as stored, and expired rules that haven't been
+// reaped yet are counted. SizeBytes comes from the collection figures, which ArangoDB
+// updates in the background, so it can lag behind recent writes.
+func (a *Adapter) Stats(ctx context.Context) (stats *Stats, err error) {
+	ctx, op := a.startOperation(ctx, "Stats")
+	defer func() {
+		if stats != nil {
+			op.setRuleCount(int(stats.Rules))
+		}
+		op.end(err)
+	}()
+
+	stats = &Stats{RulesByPtype: make(map[string]int64)}
+	names := a.routing.names()
+	ptype := "NOT_NULL(" + a.mapping.ptypeExpr() + ", \"\")"
+
+	query := "FOR doc IN @@collection FILTER " + notDeleted +
+		" COLLECT ptype = " + ptype + " WITH COUNT INTO n RETURN { ptype, n }"
+	for _, name := range names {
+		err := a.readDocuments(ctx, query, map[string]interface{}{"@collection": name}, func(raw json.RawMessage) error {
+			var count struct {
+				Ptype string `json:"ptype"`
+				N     int64  `json:"n"`
+			}
+			if err := json.Unmarshal(raw, &count); err != nil {
+				return err
+			}
+			stats.RulesByPtype[count.Ptype] += count.N
+			stats.Rules += count.N
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	policies := notDeleted + " && STARTS_WITH(" + ptype + ", \"p\")"
+	for i, count := range []*int64{&stats.Subjects, &stats.Objects} {
+		if *count, err = a.countDistinct(ctx, names, policies, a.mapping.anyFieldExpr(i)); err != nil {
+			return nil, err
+		}
+	}
+
+	if stats.TopRoles, err = a.topRoles(ctx, names, ptype); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		size, err := a.collectionSize(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		stats.SizeBytes += size
+	}
+	return stats, nil
+}
+
+// countDistinct counts the distinct non-empty values of expr among the documents matching
+// conditions in the named collections. Everything is counted by ArangoDB in one query, so
+// values stored in several collections are counted once and only the count is sent back.
+func (a *Adapter) countDistinct(ctx context.Context, names []string, conditions, expr string) (int64, error) {
+	bindVars := make(map[string]interface{}, len(names))
+	subqueries := make([]string, len(names))
+	for i, name := range names {
+		collection := fmt.Sprintf("@@collection%d", i)
+		subqueries[i] = "(FOR doc IN " + collection + " FILTER " + conditions + " && " + expr + " NOT IN [null, \"\"]" +
+			" COLLECT value = " + expr + " RETURN value)"
+		bindVars[collection[1:]] = name
+	}
+	query := "FOR value IN UNION_DISTINCT([], " + strings.Join(subqueries, ", ") + ")" +
+		" COLLECT WITH COUNT INTO n RETURN n"
+
+	var n int64
+	err := a.readDocuments(ctx, query, bindVars, func(raw json.RawMessage) error {
+		return json.Unmarshal(raw, &n)
+	})
+	return n, err
+}
+
+// topRoles returns the roles of grouping rules with the most members. A ptype is stored in
+// one collection only, so the top roles of each collection are enough to find the overall ones.
+func (a *Adapter) topRoles(ctx context.Context, names []string, ptype string) ([]RoleCount, error) {
+	role := a.mapping.anyFieldExpr(1)
+	query := "FOR doc IN @@collection FILTER " + notDeleted + " && STARTS_WITH(" + ptype + ", \"g\") && " + role + " NOT IN [null, \"\"]" +
+		" COLLECT ptype = " + ptype + ", role = " + role + " WITH COUNT INTO members" +
+		" SORT members DESC LIMIT @limit RETURN { ptype, role, members }"
+
+	var roles []RoleCount
+	for _, name := range names {
+		bindVars := map[string]interface{}{"@collection": name, "limit": statsTopRoles}
+		err := a.readDocuments(ctx, query, bindVars, func(raw json.RawMessage) error {
+			var count struct {
+				Ptype   string          `json:"ptype"`
+				Role    json.RawMessage `json:"role"`
+				Members int64           `json:"members"`
+			}
+			if err := json.Unmarshal(raw, &count); err != nil {
+				return err
+			}
+			role, err := a.mapping.decodeValue(count.Ptype, 1, count.Role)
+			if err != nil {
+				return err
+			}
+			roles = append(roles, RoleCount{Ptype: count.Ptype, Role: role, Members: count.Members})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].Members != roles[j].Members {
+			return roles[i].Members > roles[j].Members
+		}
+		if roles[i].Ptype != roles[j].Ptype {
+			return roles[i].Ptype < roles[j].Ptype
+		}
+		return roles[i].Role < roles[j].Role
+	})
+	if len(roles) > statsTopRoles {
+		roles = roles[:statsTopRoles]
+	}
+	return roles, nil
+}
+
+// collectionSize returns the bytes the named collection's documents and indexes take up.
+func (a *Adapter) collectionSize(ctx context.Context, name string) (int64, error) {
+	var response struct {
+		shared.ResponseStruct `json:",inline"`
+		Figures               struct {
+			DocumentsSize int64 `json:"documentsSize"`
+			Indexes       struct {
+				Size int64 `json:"size"`
+			} `json:"indexes"`
+		} `json:"figures"`
+	}
+
+	url := connection.NewUrl("_db", a.databaseName, "_api", "collection", name, "figures")
+	resp, err := connection.CallGet(ctx, a.client.Connection(), url, &response)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Code() != http.StatusOK {
+		return 0, response.AsArangoErrorWithCode(resp.Code())
+	}
+	return response.Figures.DocumentsSize + response.Figures.Indexes.Size, nil
+}
@@ -0,0 +1,50 @@
+package arangoadapter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStatsIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	policies := [][]string{
+		{"admin", "data1", "read"},
+		{"admin", "data1", "write"},
+		{"admin", "data2", "read"},
+		{"reader", "data1", "read"},
+	}
+	if err := adapter.AddPolicies("p", "p", policies); err != nil {
+		t.Fatalf("Failed to add policies: %v", err)
+	}
+	groupings := [][]string{
+		{"alice", "admin"},
+		{"bob", "reader"},
+		{"carol", "reader"},
+	}
+	if err := adapter.AddPolicies("g", "g", groupings); err != nil {
+		t.Fatalf("Failed to add groupings: %v", err)
+	}
+
+	stats, err := adapter.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.Rules != 7 || stats.RulesByPtype["p"] != 4 || stats.RulesByPtype["g"] != 3 {
+		t.Errorf("Expected 4 p and 3 g rules, got %d in total and %v", stats.Rules, stats.RulesByPtype)
+	}
+	if stats.Subjects != 2 || stats.Objects != 2 {
+		t.Errorf("Expected 2 subjects and 2 objects, got %d and %d", stats.Subjects, stats.Objects)
+	}
+	if len(stats.TopRoles) != 2 {
+		t.Fatalf("Expected 2 roles, got %v", stats.TopRoles)
+	}
+	if top := stats.TopRoles[0]; top.Ptype != "g" || top.Role != "reader" || top.Members != 2 {
+		t.Errorf("Expected reader with 2 members first, got %+v", top)
+	}
+	if stats.SizeBytes < 0 {
+		t.Errorf("Expected a non-negative size, got %d", stats.SizeBytes)
+	}
+}
@@ -0,0 +1,68 @@
+package arangoadapter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope used for the adapter's spans.
+const tracerName = "github.com/DenisBytes/arango-adapter"
+
+// Span attribute keys. The db.* keys follow the OpenTelemetry database conventions.
+const (
+	attrDBSystem      = attribute.Key("db.system")
+	attrDBNamespace   = attribute.Key("db.namespace")
+	attrDBCollection  = attribute.Key("db.collection.name")
+	attrDBQueryText   = attribute.Key("db.query.text")
+	attrSection       = attribute.Key("casbin.section")
+	attrPtype         = attribute.Key("casbin.ptype")
+	attrRuleCount     = attribute.Key("casbin.rule_count")
+	attrTransactionID = attribute.Key("arangodb.transaction.id")
+)
+
+// newTracer returns a tracer from tp, or a no-op tracer when tp is nil.
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// startSpan starts a client span as a child of ctx with the common database attributes.
+func (a *Adapter) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attrDBSystem.String("arangodb"),
+		attrDBNamespace.String(a.databaseName),
+		attrDBCollection.String(a.collectionName),
+	)
+	if a.transaction != nil {
+		attrs = append(attrs, attrTransactionID.String(string(a.transaction.ID())))
+	}
+
+	return a.tracer.Start(ctx, "arangoadapter."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ruleAttrs returns the span attributes describing a rule operation.
+func ruleAttrs(sec string, ptype string, count int) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attrSection.String(sec),
+		attrPtype.String(ptype),
+		attrRuleCount.Int(count),
+	}
+}
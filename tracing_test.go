@@ -0,0 +1,73 @@
+package arangoadapter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartOperation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	a := &Adapter{
+		databaseName:   "casbin",
+		collectionName: "casbin_rule",
+		tracer:         newTracer(tp),
+		metrics:        nopMetrics{},
+		logger:         slog.New(discardHandler{}),
+	}
+
+	// The span should be a child of whatever the caller passed in
+	parentCtx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	_, op := a.startOperation(parentCtx, "AddPolicy", ruleAttrs("p", "p", 1)...)
+	op.end(errors.New("boom"))
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != "arangoadapter.AddPolicy" {
+		t.Errorf("Unexpected span name %q", span.Name())
+	}
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Operation span should be a child of the caller's span")
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("Expected error status, got %v", span.Status().Code)
+	}
+
+	attrs := map[string]string{}
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	expected := map[string]string{
+		"db.system":          "arangodb",
+		"db.namespace":       "casbin",
+		"db.collection.name": "casbin_rule",
+		"casbin.ptype":       "p",
+		"casbin.rule_count":  "1",
+	}
+	for key, want := range expected {
+		if attrs[key] != want {
+			t.Errorf("Attribute %s: expected %q, got %q", key, want, attrs[key])
+		}
+	}
+}
+
+func TestNoopTracer(t *testing.T) {
+	a := &Adapter{tracer: newTracer(nil), metrics: nopMetrics{}, logger: slog.New(discardHandler{})}
+
+	// Should be safe to use without a tracer provider
+	_, op := a.startOperation(context.Background(), "LoadPolicy")
+	op.setRuleCount(3)
+	op.end(nil)
+}
@@ -0,0 +1,72 @@
+package arangoadapter
+
+import (
+	"github.com/arangodb/go-driver/v2/arangodb"
+)
+
+// validationMessage is returned by ArangoDB for documents that don't pass the schema.
+const validationMessage = "document is not a valid Casbin rule"
+
+// stringAttributes are the adapter's own attributes holding strings.
+var stringAttributes = []string{"expiresAt", "createdAt", "updatedAt", "createdBy", "deletedAt", "deletedBy"}
+
+// validationSchema returns the collection schema for rule collections, or nil when
+// validation isn't enabled.
+func (a *Adapter) validationSchema() *arangodb.CollectionSchemaOptions {
+	if a.validationLevel == "" {
+		return nil
+	}
+	return &arangodb.CollectionSchemaOptions{
+		Rule:    a.mapping.schemaRule(),
+		Level:   a.validationLevel,
+		Message: validationMessage,
+	}
+}
+
+// schemaRule returns a JSON schema that documents written by the adapter satisfy: a non-empty
+// ptype, string values and metadata. Values stored through a codec can have any type.
+// Other attributes are allowed, so the collection can be shared with other data.
+func (m *mapping) schemaRule() map[string]interface{} {
+	properties := map[string]interface{}{
+		m.ptypeField: map[string]interface{}{"type": "string", "minLength": 1},
+		"labels": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		},
+	}
+	for _, name := range stringAttributes {
+		// Cleared timestamps may be written as null before being dropped
+		properties[name] = map[string]interface{}{"type": []string{"string", "null"}}
+	}
+
+	// Whether each value attribute only ever holds strings
+	typed := make(map[string]bool)
+	for _, name := range fieldNames {
+		typed[name] = true
+	}
+	for _, fields := range m.fields {
+		for _, name := range fields {
+			typed[name] = true
+		}
+	}
+	for ptype, codecs := range m.codecs {
+		fields := m.fieldsFor(ptype)
+		for i := range codecs {
+			typed[fields[i]] = false
+		}
+	}
+
+	for name, isString := range typed {
+		if isString {
+			properties[name] = map[string]interface{}{"type": "string"}
+		} else {
+			properties[name] = map[string]interface{}{}
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{m.ptypeField},
+	}
+}
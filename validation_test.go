@@ -0,0 +1,67 @@
+package arangoadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arangodb/go-driver/v2/arangodb"
+)
+
+func TestSchemaRule(t *testing.T) {
+	m, err := newMapping(NewConfig(
+		WithPtypeField("type"),
+		WithFieldMapping("p2", "subject", "level"),
+		WithValueCodec("p2", 1, JSONCodec{}),
+		WithValueCodec("p", 3, JSONCodec{}),
+	))
+	if err != nil {
+		t.Fatalf("Failed to create mapping: %v", err)
+	}
+
+	rule := m.schemaRule()
+	if required := rule["required"].([]string); len(required) != 1 || required[0] != "type" {
+		t.Errorf("Expected the ptype field to be required, got %v", required)
+	}
+
+	properties := rule["properties"].(map[string]interface{})
+	stringTyped := map[string]bool{"v0": true, "v1": true, "subject": true, "v3": false, "level": false}
+	for name, want := range stringTyped {
+		property, ok := properties[name].(map[string]interface{})
+		if !ok {
+			t.Errorf("Expected a property for %s", name)
+			continue
+		}
+		if got := property["type"] == "string"; got != want {
+			t.Errorf("Expected %s string typed to be %v, got %v", name, want, property)
+		}
+	}
+}
+
+func TestSchemaValidationIntegration(t *testing.T) {
+	adapter := setupTestAdapter(t, WithSchemaValidation(arangodb.CollectionSchemaLevelStrict))
+	defer teardownTestAdapter(t, adapter)
+	ctx := context.Background()
+
+	if err := adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Valid rules should be accepted: %v", err)
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"missing ptype": {"v0": "alice", "v1": "data1"},
+		"empty ptype":   {"ptype": "", "v0": "alice"},
+		"numeric value": {"ptype": "p", "v0": 42},
+	}
+	for name, doc := range invalid {
+		if _, err := adapter.collection.CreateDocument(ctx, doc); err == nil {
+			t.Errorf("Expected a document with %s to be rejected", name)
+		}
+	}
+
+	props, err := adapter.collection.Properties(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read collection properties: %v", err)
+	}
+	if props.Schema == nil || props.Schema.Level != arangodb.CollectionSchemaLevelStrict {
+		t.Errorf("Expected a strict schema, got %+v", props.Schema)
+	}
+}